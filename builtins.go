@@ -1,9 +1,5 @@
 package parens
 
-import (
-	"fmt"
-)
-
 var (
 	_ Analyzer = (*BuiltinAnalyzer)(nil)
 	_ Expander = (*builtinExpander)(nil)
@@ -28,15 +24,29 @@ func (ba BuiltinAnalyzer) Analyze(env *Env, form Any) (Expr, error) {
 	}
 
 	switch f := form.(type) {
+	case AnnotatedSymbol:
+		return ba.Analyze(env, f.Symbol)
+
 	case Symbol:
-		v := env.resolve(string(f))
-		if v == nil {
-			return nil, Error{
-				Cause:   ErrNotFound,
-				Message: string(f),
-			}
+		if slot, found := env.resolveLocal(string(f)); found {
+			return &LocalExpr{Name: string(f), Slot: slot}, nil
+		} else if err := env.checkGlobal(string(f)); err != nil {
+			return nil, err
+		}
+
+		if v, found := env.Var(string(f)); found {
+			// resolve to the var cell so that evaluation need not look up the
+			// globals and sees re-definitions.
+			return &VarExpr{Name: string(f), Var: v}, nil
+		} else if env.isPending(string(f)) {
+			// var will be created by the def under analysis.
+			return &VarExpr{Name: string(f)}, nil
+		}
+
+		return nil, Error{
+			Cause:   ErrNotFound,
+			Message: string(f),
 		}
-		return &ConstExpr{Const: v}, nil
 
 	case Seq:
 		cnt, err := f.Count()
@@ -46,7 +56,11 @@ func (ba BuiltinAnalyzer) Analyze(env *Env, form Any) (Expr, error) {
 			break
 		}
 
-		return ba.analyzeSeq(env, f)
+		expr, err := ba.analyzeSeq(env, f)
+		if err != nil {
+			return nil, err
+		}
+		return env.cover(f, expr), nil
 	}
 
 	return &ConstExpr{Const: form}, nil
@@ -62,27 +76,37 @@ func (ba BuiltinAnalyzer) analyzeSeq(env *Env, seq Seq) (Expr, error) {
 	// The call target may be a special form.  In this case, we need to get the
 	// corresponding parser function, which will take care of parsing/analyzing
 	// the tail.
-	if sym, ok := first.(Symbol); ok {
+	if sym, ok := asSymbol(first); ok {
 		if parse, found := ba.SpecialForms[string(sym)]; found {
+			if err := env.checkSpecial(string(sym)); err != nil {
+				return nil, err
+			}
+
 			next, err := seq.Next()
 			if err != nil {
 				return nil, err
 			}
+
+			defer env.withPos(seq)()
 			return parse(env, next)
 		}
 	}
 
 	// Call target is not a special form and must be a Invokable.  Analyze
-	// the arguments and create an InvokeExpr.
-	ie := InvokeExpr{Name: fmt.Sprintf("%s", first)}
+	// the arguments and create an InvokeExpr.  Sub-forms are analyzed using
+	// the env so that they are expanded and traced like special form args.
+	ie := InvokeExpr{Name: formString(first)}
+	if p, ok := seq.(Positional); ok {
+		ie.Pos = p.Pos()
+	}
 	err = ForEach(seq, func(item Any) (done bool, err error) {
 		if ie.Target == nil {
-			ie.Target, err = ba.Analyze(env, first)
+			ie.Target, err = env.Analyze(first)
 			return
 		}
 
 		var arg Expr
-		if arg, err = ba.Analyze(env, item); err == nil {
+		if arg, err = env.Analyze(item); err == nil {
 			ie.Args = append(ie.Args, arg)
 		}
 		return
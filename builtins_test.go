@@ -10,6 +10,11 @@ import (
 func TestBasicAnalyzer_Analyze(t *testing.T) {
 	t.Parallel()
 
+	env := parens.New(parens.WithGlobals(map[string]parens.Any{
+		"str": parens.String("hello"),
+	}, nil))
+	strVar, _ := env.Var("str")
+
 	table := []struct {
 		title   string
 		form    parens.Any
@@ -24,7 +29,7 @@ func TestBasicAnalyzer_Analyze(t *testing.T) {
 		{
 			title: "Symbol",
 			form:  parens.Symbol("str"),
-			want:  &parens.ConstExpr{Const: parens.String("hello")},
+			want:  &parens.VarExpr{Name: "str", Var: strVar},
 		},
 		{
 			title:   "Unknown Symbol",
@@ -54,10 +59,6 @@ func TestBasicAnalyzer_Analyze(t *testing.T) {
 
 	for _, tt := range table {
 		t.Run(tt.title, func(t *testing.T) {
-			env := parens.New(parens.WithGlobals(map[string]parens.Any{
-				"str": parens.String("hello"),
-			}, nil))
-
 			az := &parens.BuiltinAnalyzer{}
 			got, err := az.Analyze(env, tt.form)
 			if (err != nil) != tt.wantErr {
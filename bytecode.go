@@ -0,0 +1,582 @@
+package parens
+
+import (
+	"fmt"
+	"strings"
+)
+
+var (
+	_ Analyzer = (*Compiler)(nil)
+	_ Expr     = (*Bytecode)(nil)
+)
+
+// NewCompiler returns an Analyzer that compiles the expressions produced by
+// the base Analyzer into Bytecode. If base is nil, the builtin analyzer with
+// the default special forms is used. Use with WithAnalyzer() to evaluate all
+// forms using the bytecode VM instead of walking the Expr trees.
+func NewCompiler(base Analyzer) *Compiler {
+	if base == nil {
+		base = newBuiltinAnalyzer()
+	}
+	return &Compiler{Base: base}
+}
+
+// Compiler implements Analyzer by compiling the Expr returned by the Base
+// analyzer into Bytecode.
+type Compiler struct {
+	Base Analyzer
+}
+
+// Analyze analyzes the form using the base Analyzer and compiles the result.
+func (c Compiler) Analyze(env *Env, form Any) (Expr, error) {
+	expr, err := c.Base.Analyze(env, form)
+	if err != nil || expr == nil {
+		return expr, err
+	}
+	return CompileExpr(expr)
+}
+
+// CompileExpr compiles the expression tree into Bytecode. Expressions of
+// types unknown to the compiler are embedded as is and evaluated by calling
+// their Eval method.
+func CompileExpr(expr Expr) (*Bytecode, error) {
+	if bc, ok := expr.(*Bytecode); ok {
+		return bc, nil
+	}
+
+	c := &compiler{bc: &Bytecode{}}
+	if err := c.compile(expr); err != nil {
+		return nil, err
+	}
+	return c.bc, nil
+}
+
+// Bytecode is a compiled form of an expression tree that is executed by a
+// stack based virtual machine when evaluated. Evaluating Bytecode produces
+// the same result as evaluating the Expr it was compiled from.
+type Bytecode struct {
+	code     []instr
+	consts   []Any
+	names    []string
+	aux      []interface{}
+	maxStack int
+}
+
+// Eval executes the bytecode against the env. Operands are pushed on the
+// value stack of the env that is shared with the bytecode of the functions
+// invoked, hence evaluation does not allocate a stack per call.
+func (bc *Bytecode) Eval(env *Env) (res Any, err error) {
+	base := len(env.vals)
+	if cap(env.vals)-base < bc.maxStack {
+		grown := make([]Any, base, 2*cap(env.vals)+bc.maxStack)
+		copy(grown, env.vals)
+		env.vals = grown
+	}
+	stack := env.vals
+
+	// restore functions for the active binding forms. Bindings must be
+	// restored even if the execution fails midway.
+	var restores []func()
+	defer func() {
+		for i := len(restores) - 1; i >= 0; i-- {
+			restores[i]()
+		}
+		env.vals = stack
+		env.release(base)
+	}()
+
+	for pc := 0; pc < len(bc.code); pc++ {
+		in := bc.code[pc]
+
+		switch in.op {
+		case opConst:
+			stack = append(stack, bc.consts[in.a])
+
+		case opPop:
+			stack[len(stack)-1] = nil
+			stack = stack[:len(stack)-1]
+
+		case opStep:
+			if err := env.step(); err != nil {
+				return nil, err
+			}
+
+		case opNilify:
+			if stack[len(stack)-1] == nil {
+				stack[len(stack)-1] = Nil{}
+			}
+
+		case opLocal:
+			var locals []Any
+			if len(env.stack) > 0 {
+				locals = env.stack[len(env.stack)-1].Locals
+			}
+			if in.a >= len(locals) {
+				return nil, Error{
+					Cause:   ErrNotFound,
+					Message: bc.names[in.b],
+				}
+			}
+			stack = append(stack, locals[in.a])
+
+		case opStore:
+			if len(env.stack) == 0 {
+				return nil, Error{
+					Cause:   ErrNotFound,
+					Message: "no stack frame for local " + bc.names[in.b],
+				}
+			}
+			env.stack[len(env.stack)-1].Locals[in.a] = stack[len(stack)-1]
+			stack[len(stack)-1] = nil
+			stack = stack[:len(stack)-1]
+
+		case opVar:
+			v, err := bc.aux[in.a].(*VarExpr).Eval(env)
+			if err != nil {
+				return nil, err
+			}
+			stack = append(stack, v)
+
+		case opJump:
+			pc = in.a - 1
+
+		case opJumpIfFalse:
+			test := stack[len(stack)-1]
+			stack[len(stack)-1] = nil
+			stack = stack[:len(stack)-1]
+			if !IsTruthy(test) {
+				pc = in.a - 1
+			}
+
+		case opInvoke:
+			// functions are passed the window of the stack holding the args
+			// since they do not retain them (See GoFunc). Other invokables
+			// may retain the args and hence get a copy.
+			base := len(stack) - in.a - 1
+			target, args := stack[base], stack[base+1:len(stack):len(stack)]
+			switch target.(type) {
+			case *Fn, GoFunc:
+			default:
+				args = append([]Any(nil), args...)
+			}
+
+			// values pushed by the invoked bytecode go above the args.
+			site := bc.aux[in.b].(*InvokeExpr)
+			env.vals = stack
+			v, err := env.invoke(site.Name, site.Pos, target, args)
+			stack = env.vals
+			if err != nil {
+				return nil, err
+			}
+
+			for i := base + 1; i < len(stack); i++ {
+				stack[i] = nil // allow the args to be garbage collected.
+			}
+			stack[base] = v
+			stack = stack[:base+1]
+
+		case opDef:
+			de := bc.aux[in.a].(*DefExpr)
+			env.vals = stack
+			v, err := de.bind(env, stack[len(stack)-1])
+			stack = env.vals
+			if err != nil {
+				return nil, err
+			}
+			stack[len(stack)-1] = v
+
+		case opBind:
+			names := bc.aux[in.a].([]string)
+			vals := make(map[string]Any, len(names))
+			for i, name := range names {
+				vals[name] = stack[len(stack)-len(names)+i]
+				stack[len(stack)-len(names)+i] = nil
+			}
+			stack = stack[:len(stack)-len(names)]
+
+			restore, err := env.Bind(vals)
+			if err != nil {
+				return nil, err
+			}
+			restores = append(restores, restore)
+
+		case opUnbind:
+			restores[len(restores)-1]()
+			restores = restores[:len(restores)-1]
+
+		case opEval:
+			env.vals = stack
+			v, err := bc.aux[in.a].(Expr).Eval(env)
+			stack = env.vals
+			if err != nil {
+				return nil, err
+			}
+			stack = append(stack, v)
+
+		default:
+			return nil, fmt.Errorf("invalid opcode %d at %d", in.op, pc)
+		}
+	}
+
+	return stack[len(stack)-1], nil
+}
+
+// String returns a human-readable disassembly of the bytecode.
+func (bc *Bytecode) String() string {
+	var b strings.Builder
+	for pc, in := range bc.code {
+		fmt.Fprintf(&b, "%04d %-12s", pc, in.op)
+		switch in.op {
+		case opConst:
+			fmt.Fprintf(&b, " %d ; %v", in.a, bc.consts[in.a])
+		case opLocal, opStore:
+			fmt.Fprintf(&b, " %d ; %s", in.a, bc.names[in.b])
+		case opInvoke:
+			fmt.Fprintf(&b, " %d ; %s", in.a, bc.aux[in.b].(*InvokeExpr).Name)
+		case opJump, opJumpIfFalse, opVar, opDef, opBind, opEval:
+			fmt.Fprintf(&b, " %d", in.a)
+		}
+		b.WriteString("\n")
+	}
+	return b.String()
+}
+
+type opcode uint8
+
+const (
+	opConst       opcode = iota // push consts[a]
+	opPop                       // discard top of the stack
+	opNilify                    // replace native nil on top of the stack with Nil{}
+	opLocal                     // push value of local slot a (named names[b])
+	opStore                     // pop value into local slot a (named names[b])
+	opVar                       // push value of var expression aux[a]
+	opJump                      // jump to a
+	opJumpIfFalse               // pop and jump to a if not truthy
+	opInvoke                    // invoke target with a args at call site aux[b]
+	opDef                       // define top of the stack using aux[a]
+	opBind                      // pop values and bind names in aux[a]
+	opUnbind                    // restore the bindings of the last opBind
+	opEval                      // push result of evaluating aux[a]
+	opStep                      // count an evaluation step against the limits
+)
+
+var opNames = [...]string{
+	"CONST", "POP", "NILIFY", "LOCAL", "STORE", "VAR", "JUMP", "JUMP_IF_FALSE",
+	"INVOKE", "DEF", "BIND", "UNBIND", "EVAL", "STEP",
+}
+
+func (op opcode) String() string {
+	if int(op) < len(opNames) {
+		return opNames[op]
+	}
+	return fmt.Sprintf("OP(%d)", op)
+}
+
+type instr struct {
+	op   opcode
+	a, b int
+}
+
+type compiler struct {
+	bc    *Bytecode
+	depth int
+}
+
+func (c *compiler) compile(expr Expr) error {
+	switch e := expr.(type) {
+	case *Bytecode:
+		c.splice(e)
+
+	case ConstExpr:
+		c.emitConst(e.Const)
+	case *ConstExpr:
+		c.emitConst(e.Const)
+
+	case QuoteExpr:
+		c.emitConst(e.Form)
+	case *QuoteExpr:
+		c.emitConst(e.Form)
+
+	case LocalExpr:
+		c.emit(opLocal, e.Slot, c.name(e.Name))
+	case *LocalExpr:
+		c.emit(opLocal, e.Slot, c.name(e.Name))
+
+	case VarExpr:
+		c.emit(opVar, c.addAux(&e), 0)
+	case *VarExpr:
+		c.emit(opVar, c.addAux(e), 0)
+
+	case *coverExpr:
+		body, err := CompileExpr(e.Expr)
+		if err != nil {
+			return err
+		}
+		c.emit(opEval, c.addAux(&coverExpr{Expr: body, count: e.count}), 0)
+
+	case LetExpr:
+		return c.compileLet(&e)
+	case *LetExpr:
+		return c.compileLet(e)
+
+	case IfExpr:
+		return c.compileIf(&e)
+	case *IfExpr:
+		return c.compileIf(e)
+
+	case DoExpr:
+		return c.compileDo(&e)
+	case *DoExpr:
+		return c.compileDo(e)
+
+	case InvokeExpr:
+		return c.compileInvoke(&e)
+	case *InvokeExpr:
+		return c.compileInvoke(e)
+
+	case DefExpr:
+		return c.compileDef(&e)
+	case *DefExpr:
+		return c.compileDef(e)
+
+	case BindingExpr:
+		return c.compileBinding(&e)
+	case *BindingExpr:
+		return c.compileBinding(e)
+
+	case FnExpr:
+		return c.compileFn(&e)
+	case *FnExpr:
+		return c.compileFn(e)
+
+	case GoExpr:
+		return c.compileGo(&e)
+	case *GoExpr:
+		return c.compileGo(e)
+
+	default:
+		c.emit(opEval, c.addAux(expr), 0)
+	}
+
+	return nil
+}
+
+func (c *compiler) compileIf(ife *IfExpr) error {
+	if ife.Test == nil {
+		return c.compileOrNil(ife.Else)
+	}
+
+	if err := c.compile(ife.Test); err != nil {
+		return err
+	}
+	jumpElse := c.emit(opJumpIfFalse, -1, 0)
+
+	if err := c.compileOrNil(ife.Then); err != nil {
+		return err
+	}
+	jumpEnd := c.emit(opJump, -1, 0)
+	c.depth-- // only one of the branches is executed.
+
+	c.bc.code[jumpElse].a = len(c.bc.code)
+	if err := c.compileOrNil(ife.Else); err != nil {
+		return err
+	}
+	c.bc.code[jumpEnd].a = len(c.bc.code)
+	return nil
+}
+
+func (c *compiler) compileDo(de *DoExpr) error {
+	if len(de.Exprs) == 0 {
+		c.emitConst(Nil{})
+		return nil
+	}
+
+	for i, expr := range de.Exprs {
+		if i > 0 {
+			c.emit(opPop, 0, 0)
+		}
+		c.emit(opStep, 0, 0)
+		if err := c.compile(expr); err != nil {
+			return err
+		}
+	}
+	c.emit(opNilify, 0, 0)
+	return nil
+}
+
+func (c *compiler) compileInvoke(ie *InvokeExpr) error {
+	if err := c.compile(ie.Target); err != nil {
+		return err
+	}
+
+	for _, arg := range ie.Args {
+		if err := c.compile(arg); err != nil {
+			return err
+		}
+	}
+
+	// call site carries the name and position of the invocation.
+	c.emit(opInvoke, len(ie.Args), c.addAux(&InvokeExpr{Name: ie.Name, Pos: ie.Pos}))
+	return nil
+}
+
+func (c *compiler) compileDef(de *DefExpr) error {
+	trimmed := *de
+	trimmed.Name = strings.TrimSpace(de.Name)
+	if trimmed.Name == "" {
+		// let the DefExpr report the error without evaluating the value.
+		c.emit(opEval, c.addAux(de), 0)
+		return nil
+	}
+
+	if err := c.compile(de.Value); err != nil {
+		return err
+	}
+	c.emit(opDef, c.addAux(&trimmed), 0)
+	return nil
+}
+
+func (c *compiler) compileBinding(be *BindingExpr) error {
+	for _, val := range be.Values {
+		if err := c.compile(val); err != nil {
+			return err
+		}
+	}
+	c.emit(opBind, c.addAux(be.Names), len(be.Names))
+
+	if err := c.compile(be.Body); err != nil {
+		return err
+	}
+	c.emit(opUnbind, 0, 0)
+	return nil
+}
+
+func (c *compiler) compileLet(le *LetExpr) error {
+	if le.Frame != nil {
+		// let with its own frame is evaluated as is with compiled parts.
+		compiled := *le
+		compiled.Values = make([]Expr, len(le.Values))
+		for i, val := range le.Values {
+			bc, err := CompileExpr(val)
+			if err != nil {
+				return err
+			}
+			compiled.Values[i] = bc
+		}
+
+		body, err := CompileExpr(le.Body)
+		if err != nil {
+			return err
+		}
+		compiled.Body = body
+
+		c.emit(opEval, c.addAux(&compiled), 0)
+		return nil
+	}
+
+	for i, val := range le.Values {
+		if err := c.compile(val); err != nil {
+			return err
+		}
+		c.emit(opStore, le.Slots[i], c.name("let"))
+	}
+	return c.compile(le.Body)
+}
+
+func (c *compiler) compileFn(fe *FnExpr) error {
+	body, err := CompileExpr(fe.Body)
+	if err != nil {
+		return err
+	}
+
+	compiled := *fe
+	compiled.Body = body
+	c.emit(opEval, c.addAux(&compiled), 0)
+	return nil
+}
+
+func (c *compiler) compileGo(ge *GoExpr) error {
+	body, err := CompileExpr(ge.Value)
+	if err != nil {
+		return err
+	}
+
+	c.emit(opEval, c.addAux(&GoExpr{Value: body}), 0)
+	return nil
+}
+
+func (c *compiler) compileOrNil(expr Expr) error {
+	if expr == nil {
+		c.emitConst(Nil{})
+		return nil
+	}
+	return c.compile(expr)
+}
+
+// splice inlines the instructions of an already compiled Bytecode.
+func (c *compiler) splice(other *Bytecode) {
+	offset := len(c.bc.code)
+	consts, names, aux := len(c.bc.consts), len(c.bc.names), len(c.bc.aux)
+
+	for _, in := range other.code {
+		switch in.op {
+		case opConst:
+			in.a += consts
+		case opLocal, opStore:
+			in.b += names
+		case opInvoke:
+			in.b += aux
+		case opJump, opJumpIfFalse:
+			in.a += offset
+		case opVar, opDef, opBind, opEval:
+			in.a += aux
+		}
+		c.bc.code = append(c.bc.code, in)
+	}
+
+	c.bc.consts = append(c.bc.consts, other.consts...)
+	c.bc.names = append(c.bc.names, other.names...)
+	c.bc.aux = append(c.bc.aux, other.aux...)
+
+	if c.depth+other.maxStack > c.bc.maxStack {
+		c.bc.maxStack = c.depth + other.maxStack
+	}
+	c.depth++
+}
+
+func (c *compiler) emitConst(v Any) {
+	c.bc.consts = append(c.bc.consts, v)
+	c.emit(opConst, len(c.bc.consts)-1, 0)
+}
+
+// emit appends the instruction and returns its address. Stack depth is
+// tracked to pre-allocate the stack of the right size during execution.
+func (c *compiler) emit(op opcode, a, b int) int {
+	switch op {
+	case opConst, opLocal, opVar, opEval:
+		c.depth++
+	case opPop, opStore, opJumpIfFalse:
+		c.depth--
+	case opInvoke:
+		c.depth -= a
+	case opBind:
+		c.depth -= b
+	}
+
+	if c.depth > c.bc.maxStack {
+		c.bc.maxStack = c.depth
+	}
+
+	c.bc.code = append(c.bc.code, instr{op: op, a: a, b: b})
+	return len(c.bc.code) - 1
+}
+
+func (c *compiler) name(name string) int {
+	c.bc.names = append(c.bc.names, name)
+	return len(c.bc.names) - 1
+}
+
+func (c *compiler) addAux(v interface{}) int {
+	c.bc.aux = append(c.bc.aux, v)
+	return len(c.bc.aux) - 1
+}
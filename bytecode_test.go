@@ -0,0 +1,167 @@
+package parens_test
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/spy16/parens"
+	"github.com/spy16/parens/reader"
+)
+
+func TestCompileExpr(t *testing.T) {
+	t.Parallel()
+
+	t.Run("Idempotent", func(t *testing.T) {
+		bc, err := parens.CompileExpr(&parens.ConstExpr{Const: parens.Int64(1)})
+		requireNoErr(t, err)
+
+		again, err := parens.CompileExpr(bc)
+		requireNoErr(t, err)
+		if again != bc {
+			t.Errorf("compiling bytecode must return it unmodified")
+		}
+	})
+
+	t.Run("Nested", func(t *testing.T) {
+		env := parens.New(parens.WithAnalyzer(parens.NewCompiler(nil)),
+			parens.WithGlobals(map[string]parens.Any{
+				"*depth*": parens.Int64(0),
+				"list":    parens.GoFunc(listFn),
+			}, nil))
+
+		form := readOne(t, `((fn (x) (binding (*depth* x) (list x *depth*))) 1)`)
+		expr, err := env.Analyze(form)
+		requireNoErr(t, err)
+
+		if _, ok := expr.(*parens.Bytecode); !ok {
+			t.Fatalf("expecting *parens.Bytecode, got %#v", expr)
+		}
+
+		got, err := evalExpr(t, expr, env)
+		requireNoErr(t, err)
+		assertEqual(t, parens.NewList(parens.Int64(1), parens.Int64(1)), got)
+	})
+
+	t.Run("UnknownExpr", func(t *testing.T) {
+		got, err := evalExpr(t, customExpr{}, parens.New())
+		requireNoErr(t, err)
+		assertEqual(t, parens.Keyword("custom"), got)
+	})
+}
+
+func BenchmarkEval(b *testing.B) {
+	programs := []struct {
+		name string
+		src  string
+	}{
+		{name: "Calls", src: `((fn (x y) (list (list x y) (list y x))) 1 2)`},
+		{name: "Fib", src: `((fn fib (n) (if (< n 2) n (+ (fib (- n 1)) (fib (- n 2))))) 12)`},
+	}
+
+	for _, be := range backends {
+		for _, p := range programs {
+			b.Run(be.name+"/"+p.name, func(b *testing.B) {
+				env := parens.New(append(be.opts, parens.WithCore(), parens.WithGlobals(map[string]parens.Any{
+					"list": parens.GoFunc(listFn),
+				}, nil))...)
+
+				prog, err := env.Compile(readOne(b, p.src))
+				if err != nil {
+					b.Fatalf("unexpected error: %v", err)
+				}
+
+				b.ReportAllocs()
+				b.ResetTimer()
+				for i := 0; i < b.N; i++ {
+					if _, err := prog.Run(env); err != nil {
+						b.Fatalf("unexpected error: %v", err)
+					}
+				}
+			})
+		}
+	}
+}
+
+type customExpr struct{}
+
+func (customExpr) Eval(_ *parens.Env) (parens.Any, error) { return parens.Keyword("custom"), nil }
+
+func listFn(_ *parens.Env, args ...parens.Any) (parens.Any, error) {
+	return parens.NewList(args...), nil
+}
+
+// backends are the evaluation backends the evaluation tests are run against.
+var backends = []struct {
+	name string
+	opts []parens.Option
+}{
+	{name: "TreeWalker"},
+	{name: "Bytecode", opts: []parens.Option{parens.WithAnalyzer(parens.NewCompiler(nil))}},
+}
+
+// forEachBackend runs the test once for every evaluation backend. opts must
+// be passed to parens.New() to create env using the backend.
+func forEachBackend(t *testing.T, test func(t *testing.T, opts ...parens.Option)) {
+	for _, be := range backends {
+		be := be
+		t.Run(be.name, func(t *testing.T) { test(t, be.opts...) })
+	}
+}
+
+// evalBackends evaluates the forms of src in order in a new env created with
+// opts for every evaluation backend and verifies all backends produce the
+// same result. Returns the result of the last form or the first error.
+func evalBackends(t *testing.T, src string, opts ...parens.Option) (parens.Any, error) {
+	forms, err := reader.New(strings.NewReader(src)).All()
+	requireNoErr(t, err)
+
+	var want parens.Any
+	var wantErr error
+	for i, be := range backends {
+		env := parens.New(append(append([]parens.Option{}, be.opts...), opts...)...)
+
+		var got parens.Any
+		var err error
+		for _, form := range forms {
+			if got, err = env.Eval(form); err != nil {
+				break
+			}
+		}
+
+		if i == 0 {
+			want, wantErr = got, err
+			continue
+		}
+
+		if (err != nil) != (wantErr != nil) || (err != nil && err.Error() != wantErr.Error()) {
+			t.Errorf("%s: error mismatch: got=%v, want=%v", be.name, err, wantErr)
+		} else if err == nil && sexpr(t, got) != sexpr(t, want) {
+			t.Errorf("%s: result mismatch: got=%#v, want=%#v", be.name, got, want)
+		}
+	}
+	return want, wantErr
+}
+
+func sexpr(t *testing.T, v parens.Any) string {
+	s, err := v.SExpr()
+	requireNoErr(t, err)
+	return s
+}
+
+// evalExpr evaluates the expression by walking the tree and by compiling it
+// into bytecode and verifies both produce the same result.
+func evalExpr(t *testing.T, expr parens.Expr, env *parens.Env) (parens.Any, error) {
+	want, wantErr := expr.Eval(env)
+
+	bc, err := parens.CompileExpr(expr)
+	requireNoErr(t, err)
+
+	got, gotErr := bc.Eval(env)
+	if !reflect.DeepEqual(want, got) || !reflect.DeepEqual(wantErr, gotErr) {
+		t.Errorf("bytecode result mismatch: got=(%#v, %v), want=(%#v, %v)\n%s",
+			got, gotErr, want, wantErr, bc)
+	}
+
+	return want, wantErr
+}
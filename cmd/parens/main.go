@@ -2,13 +2,21 @@ package main
 
 import (
 	"context"
+	"flag"
 	"log"
 
 	"github.com/spy16/parens"
 	"github.com/spy16/parens/repl"
 )
 
+var (
+	profile = flag.Bool("profile", false, "enable the profile-start and profile-stop functions to profile calls")
+	debug   = flag.Bool("debug", false, "enable the break and unbreak functions and the debug prompt")
+)
+
 func main() {
+	flag.Parse()
+
 	globals := map[string]parens.Any{
 		"nil":       parens.Nil{},
 		"true":      parens.Bool(true),
@@ -16,12 +24,31 @@ func main() {
 		"*version*": parens.String("1.0"),
 	}
 
-	env := parens.New(parens.WithGlobals(globals, nil))
-
-	err := repl.New(env,
+	opts := []parens.Option{
+		parens.WithGlobals(globals, nil),
+		parens.WithCore(),
+	}
+	replOpts := []repl.Option{
 		repl.WithBanner("Welcome to Parens!"),
 		repl.WithPrompts(">>", " |"),
-	).Loop(context.Background())
+	}
+
+	if *debug {
+		// debugger checks every call for breakpoints and hence is installed
+		// only on request.
+		debugger := parens.NewDebugger(nil)
+		opts = append(opts, parens.WithDebugger(debugger))
+		replOpts = append(replOpts, repl.WithDebugger(debugger))
+	}
+
+	if *profile {
+		// profiler traces every call and hence is installed only on request.
+		profiler := parens.NewProfiler()
+		opts = append(opts, parens.WithTracer(profiler))
+		replOpts = append(replOpts, repl.WithProfiler(profiler))
+	}
+
+	err := repl.New(parens.New(opts...), replOpts...).Loop(context.Background())
 
 	if err != nil {
 		log.Fatal(err)
@@ -0,0 +1,519 @@
+package parens
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"time"
+)
+
+// core is the set of functions bound in the global bindings by WithCore().
+// Globals provided using WithGlobals() take precedence.
+var core = []struct {
+	name     string
+	doc      string
+	arglists []string
+	fn       GoFunc
+}{
+	{
+		name:     "meta",
+		doc:      "Returns the metadata of obj, returns nil if there is no metadata.",
+		arglists: []string{"obj"},
+		fn:       coreMeta,
+	},
+	{
+		name:     "with-meta",
+		doc:      "Returns a copy of obj with the metadata replaced by m.",
+		arglists: []string{"obj m"},
+		fn:       coreWithMeta,
+	},
+	{
+		name:     "vary-meta",
+		doc:      "Returns a copy of obj with the metadata replaced by (apply f (meta obj) args).",
+		arglists: []string{"obj f & args"},
+		fn:       coreVaryMeta,
+	},
+	{
+		name:     "hash-map",
+		doc:      "Returns a new map with the given key-value pairs.",
+		arglists: []string{"& kvs"},
+		fn:       coreHashMap,
+	},
+	{
+		name:     "assoc",
+		doc:      "Returns a new map with the key-value pairs added to m.",
+		arglists: []string{"m k v & kvs"},
+		fn:       coreAssoc,
+	},
+	{
+		name:     "get",
+		doc:      "Returns the value mapped to k in m, or not-found (or nil) if k is not present.",
+		arglists: []string{"m k", "m k not-found"},
+		fn:       coreGet,
+	},
+	{
+		name:     "+",
+		doc:      "Returns the sum of the numbers. Returns 0 if there are no numbers. Numbers of different types are converted to the highest type in the order integer, big integer, ratio, decimal and float. A float makes the result an inexact float, e.g., (+ 0.1M 0.2) returns 0.30000000000000004.",
+		arglists: []string{"& xs"},
+		fn:       coreAdd,
+	},
+	{
+		name:     "-",
+		doc:      "Returns x minus the numbers, or the negation of x if there are no numbers. Numbers of different types are converted as by +.",
+		arglists: []string{"x & xs"},
+		fn:       coreSub,
+	},
+	{
+		name:     "*",
+		doc:      "Returns the product of the numbers. Returns 1 if there are no numbers. Numbers of different types are converted as by +.",
+		arglists: []string{"& xs"},
+		fn:       coreMul,
+	},
+	{
+		name:     "/",
+		doc:      "Returns x divided by the numbers, or the reciprocal of x if there are no numbers. Returns a ratio if integer division is not exact. Numbers of different types are converted as by +.",
+		arglists: []string{"x & xs"},
+		fn:       coreDiv,
+	},
+	{
+		name:     "==",
+		doc:      "Returns true if the numbers are numerically equal regardless of their types (e.g., (== 1 1.0 1N)).",
+		arglists: []string{"x & more"},
+		fn:       coreNumEq,
+	},
+	{
+		name:     "<",
+		doc:      "Returns true if the numbers are in monotonically increasing order.",
+		arglists: []string{"x & more"},
+		fn:       coreLess,
+	},
+	{
+		name:     ">",
+		doc:      "Returns true if the numbers are in monotonically decreasing order.",
+		arglists: []string{"x & more"},
+		fn:       coreGreater,
+	},
+	{
+		name:     "<=",
+		doc:      "Returns true if the numbers are in monotonically non-decreasing order.",
+		arglists: []string{"x & more"},
+		fn:       coreLessEq,
+	},
+	{
+		name:     ">=",
+		doc:      "Returns true if the numbers are in monotonically non-increasing order.",
+		arglists: []string{"x & more"},
+		fn:       coreGreaterEq,
+	},
+	{
+		name:     "rand",
+		doc:      "Returns a random floating point number between 0 (inclusive) and n (default 1) (exclusive).",
+		arglists: []string{"", "n"},
+		fn:       coreRand,
+	},
+	{
+		name:     "rand-int",
+		doc:      "Returns a random integer between 0 (inclusive) and n (exclusive).",
+		arglists: []string{"n"},
+		fn:       coreRandInt,
+	},
+	{
+		name:     "now",
+		doc:      "Returns the current time in milliseconds since the Unix epoch.",
+		arglists: []string{""},
+		fn:       coreNow,
+	},
+	{
+		name:     "re-find",
+		doc:      "Returns the first match of re in s or nil. Returns a list of the match and the groups if re has groups.",
+		arglists: []string{"re s"},
+		fn:       coreReFind,
+	},
+	{
+		name:     "re-matches",
+		doc:      "Returns the match if re matches the whole of s or nil. Returns a list of the match and the groups if re has groups.",
+		arglists: []string{"re s"},
+		fn:       coreReMatches,
+	},
+	{
+		name:     "re-seq",
+		doc:      "Returns a list of the successive matches of re in s or nil if there are no matches.",
+		arglists: []string{"re s"},
+		fn:       coreReSeq,
+	},
+	{
+		name:     "ns-unmap",
+		doc:      "Removes the global bindings for the given symbols. Returns nil.",
+		arglists: []string{"& syms"},
+		fn:       coreNSUnmap,
+	},
+}
+
+func bindCore(env *Env) {
+	for _, c := range core {
+		if _, found := env.Var(c.name); found {
+			continue
+		}
+
+		var arglists []Any
+		for _, al := range c.arglists {
+			var params []Any
+			for _, p := range strings.Fields(al) {
+				params = append(params, Symbol(p))
+			}
+			arglists = append(arglists, NewList(params...))
+		}
+
+		meta, err := assoc(nil,
+			Keyword("doc"), String(c.doc),
+			Keyword("arglists"), NewList(arglists...),
+		)
+		if err != nil {
+			panic(err)
+		}
+
+		env.globals.Store(c.name, NewVar(c.name, c.fn, meta))
+	}
+}
+
+func coreMeta(_ *Env, args ...Any) (Any, error) {
+	if err := checkArity("meta", args, 1, 1); err != nil {
+		return nil, err
+	}
+
+	if an, ok := args[0].(Annotated); ok {
+		if meta := an.Meta(); meta != nil {
+			return meta, nil
+		}
+	}
+	return Nil{}, nil
+}
+
+func coreWithMeta(_ *Env, args ...Any) (Any, error) {
+	if err := checkArity("with-meta", args, 2, 2); err != nil {
+		return nil, err
+	}
+	return withMeta(args[0], args[1])
+}
+
+func coreVaryMeta(env *Env, args ...Any) (Any, error) {
+	if err := checkArity("vary-meta", args, 2, -1); err != nil {
+		return nil, err
+	}
+
+	meta, err := coreMeta(env, args[0])
+	if err != nil {
+		return nil, err
+	}
+
+	fnArgs := append([]Any{meta}, args[2:]...)
+	newMeta, err := env.invoke("vary-meta", Position{}, args[1], fnArgs)
+	if err != nil {
+		return nil, err
+	}
+
+	return withMeta(args[0], newMeta)
+}
+
+func coreHashMap(env *Env, args ...Any) (Any, error) {
+	if err := env.Alloc(len(args) / 2); err != nil {
+		return nil, err
+	}
+	return NewHashMap(args...)
+}
+
+func coreAssoc(env *Env, args ...Any) (Any, error) {
+	if err := checkArity("assoc", args, 3, -1); err != nil {
+		return nil, err
+	} else if len(args)%2 != 1 {
+		return nil, Error{
+			Cause:   ErrArity,
+			Message: "assoc expects even number of forms after map",
+		}
+	}
+
+	var m Map
+	if !IsNil(args[0]) {
+		var ok bool
+		if m, ok = args[0].(Map); !ok {
+			return nil, fmt.Errorf("assoc: expecting map, not '%s'", reflect.TypeOf(args[0]))
+		}
+	}
+
+	res, err := assoc(m, args[1:]...)
+	if err != nil {
+		return nil, err
+	}
+
+	size, err := res.Count()
+	if err != nil {
+		return nil, err
+	}
+	return res, env.Alloc(size)
+}
+
+func coreGet(_ *Env, args ...Any) (Any, error) {
+	if err := checkArity("get", args, 2, 3); err != nil {
+		return nil, err
+	}
+
+	var notFound Any = Nil{}
+	if len(args) == 3 {
+		notFound = args[2]
+	}
+
+	m, ok := args[0].(Map)
+	if !ok {
+		return notFound, nil
+	}
+
+	if v, found := m.EntryAt(args[1]); found {
+		return v, nil
+	}
+	return notFound, nil
+}
+
+func coreNSUnmap(env *Env, args ...Any) (Any, error) {
+	for _, arg := range args {
+		sym, ok := asSymbol(arg)
+		if !ok {
+			return nil, fmt.Errorf("ns-unmap: expecting symbol, not '%s'", reflect.TypeOf(arg))
+		}
+		env.Undef(string(sym))
+	}
+	return Nil{}, nil
+}
+
+func withMeta(obj Any, meta Any) (Any, error) {
+	an, ok := obj.(Annotatable)
+	if !ok {
+		return nil, fmt.Errorf("value of type '%s' does not support metadata", reflect.TypeOf(obj))
+	}
+
+	if IsNil(meta) {
+		return an.WithMeta(nil)
+	}
+
+	m, ok := meta.(Map)
+	if !ok {
+		return nil, fmt.Errorf("metadata must be a map, not '%s'", reflect.TypeOf(meta))
+	}
+	return an.WithMeta(m)
+}
+
+// checkArity returns ErrArity if the number of args is not within the given
+// range. max < 0 means there is no upper limit.
+func checkArity(name string, args []Any, min, max int) error {
+	if len(args) < min || (max >= 0 && len(args) > max) {
+		return Error{
+			Cause:   ErrArity,
+			Message: fmt.Sprintf("(%d) passed to %s", len(args), name),
+		}
+	}
+	return nil
+}
+
+func coreAdd(_ *Env, args ...Any) (Any, error) {
+	return foldNumbers("+", opAdd, Int64(0), args)
+}
+
+func coreSub(_ *Env, args ...Any) (Any, error) {
+	if err := checkArity("-", args, 1, -1); err != nil {
+		return nil, err
+	} else if len(args) == 1 {
+		return foldNumbers("-", opSub, Int64(0), args)
+	}
+	return foldNumbers("-", opSub, args[0], args[1:])
+}
+
+func coreMul(_ *Env, args ...Any) (Any, error) {
+	return foldNumbers("*", opMul, Int64(1), args)
+}
+
+func coreDiv(_ *Env, args ...Any) (Any, error) {
+	if err := checkArity("/", args, 1, -1); err != nil {
+		return nil, err
+	} else if len(args) == 1 {
+		return foldNumbers("/", opDiv, Int64(1), args)
+	}
+	return foldNumbers("/", opDiv, args[0], args[1:])
+}
+
+func coreNumEq(_ *Env, args ...Any) (Any, error) {
+	return orderedNumbers("==", args, func(c int) bool { return c == 0 })
+}
+
+func coreLess(_ *Env, args ...Any) (Any, error) {
+	return orderedNumbers("<", args, func(c int) bool { return c < 0 })
+}
+
+func coreGreater(_ *Env, args ...Any) (Any, error) {
+	return orderedNumbers(">", args, func(c int) bool { return c > 0 })
+}
+
+func coreLessEq(_ *Env, args ...Any) (Any, error) {
+	return orderedNumbers("<=", args, func(c int) bool { return c <= 0 })
+}
+
+func coreGreaterEq(_ *Env, args ...Any) (Any, error) {
+	return orderedNumbers(">=", args, func(c int) bool { return c >= 0 })
+}
+
+// foldNumbers applies the operator to the numbers from left to right
+// starting with init.
+func foldNumbers(name string, op numOp, init Any, args []Any) (Any, error) {
+	res := init
+	for _, arg := range args {
+		var err error
+		if res, err = arith(op, res, arg); err != nil {
+			return nil, fmt.Errorf("%s: %w", name, err)
+		}
+	}
+	return res, nil
+}
+
+// orderedNumbers returns true if cmp holds for each pair of adjacent
+// numbers. NaN is not ordered with respect to any number.
+func orderedNumbers(name string, args []Any, cmp func(c int) bool) (Any, error) {
+	if err := checkArity(name, args, 1, -1); err != nil {
+		return nil, err
+	}
+
+	res := true
+	for i := range args {
+		if _, err := numRank(args[i]); err != nil {
+			return nil, fmt.Errorf("%s: %w", name, err)
+		} else if i == 0 || !res {
+			continue
+		}
+
+		c, ordered, err := compareNumbers(args[i-1], args[i])
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", name, err)
+		}
+		res = ordered && cmp(c)
+	}
+	return Bool(res), nil
+}
+
+func coreRand(env *Env, args ...Any) (Any, error) {
+	if err := checkArity("rand", args, 0, 1); err != nil {
+		return nil, err
+	}
+
+	n := 1.0
+	if len(args) == 1 {
+		switch v := args[0].(type) {
+		case Int64:
+			n = float64(v)
+		case Float64:
+			n = float64(v)
+		default:
+			return nil, fmt.Errorf("rand: expecting number, not '%s'", reflect.TypeOf(args[0]))
+		}
+	}
+	return Float64(env.Rand().Float64() * n), nil
+}
+
+func coreRandInt(env *Env, args ...Any) (Any, error) {
+	if err := checkArity("rand-int", args, 1, 1); err != nil {
+		return nil, err
+	}
+
+	n, ok := args[0].(Int64)
+	if !ok || n <= 0 {
+		return nil, fmt.Errorf("rand-int: expecting positive integer, not '%v'", args[0])
+	}
+	return Int64(env.Rand().Int63n(int64(n))), nil
+}
+
+func coreNow(env *Env, args ...Any) (Any, error) {
+	if err := checkArity("now", args, 0, 0); err != nil {
+		return nil, err
+	}
+	return Int64(env.Now().UnixNano() / int64(time.Millisecond)), nil
+}
+
+func coreReFind(env *Env, args ...Any) (Any, error) {
+	re, s, err := regexArgs("re-find", args)
+	if err != nil {
+		return nil, err
+	}
+	return reMatch(env, s, re.FindStringSubmatchIndex(s))
+}
+
+func coreReMatches(env *Env, args ...Any) (Any, error) {
+	re, s, err := regexArgs("re-matches", args)
+	if err != nil {
+		return nil, err
+	}
+	return reMatch(env, s, re.anchored.FindStringSubmatchIndex(s))
+}
+
+func coreReSeq(env *Env, args ...Any) (Any, error) {
+	re, s, err := regexArgs("re-seq", args)
+	if err != nil {
+		return nil, err
+	}
+
+	locs := re.FindAllStringSubmatchIndex(s, -1)
+	if len(locs) == 0 {
+		return Nil{}, nil
+	} else if err := env.Alloc(len(locs)); err != nil {
+		return nil, err
+	}
+
+	matches := make([]Any, len(locs))
+	for i, loc := range locs {
+		if matches[i], err = reMatch(env, s, loc); err != nil {
+			return nil, err
+		}
+	}
+	return NewList(matches...), nil
+}
+
+func regexArgs(name string, args []Any) (Regex, string, error) {
+	if err := checkArity(name, args, 2, 2); err != nil {
+		return Regex{}, "", err
+	}
+
+	re, ok := args[0].(Regex)
+	if !ok {
+		return Regex{}, "", fmt.Errorf("%s: expecting regex, not '%s'", name, reflect.TypeOf(args[0]))
+	}
+
+	s, ok := args[1].(String)
+	if !ok {
+		return Regex{}, "", fmt.Errorf("%s: expecting string, not '%s'", name, reflect.TypeOf(args[1]))
+	}
+	return re, string(s), nil
+}
+
+// reMatch returns the match at the location as a string if the regex has
+// no groups and as a list of the match and the groups otherwise. Groups that
+// did not participate in the match are nil.
+func reMatch(env *Env, s string, loc []int) (Any, error) {
+	if loc == nil {
+		return Nil{}, nil
+	} else if len(loc) == 2 {
+		return String(s[loc[0]:loc[1]]), env.AllocString(loc[1] - loc[0])
+	}
+
+	if err := env.Alloc(len(loc) / 2); err != nil {
+		return nil, err
+	}
+
+	groups := make([]Any, len(loc)/2)
+	for i := range groups {
+		if loc[2*i] < 0 {
+			groups[i] = Nil{}
+			continue
+		}
+
+		if err := env.AllocString(loc[2*i+1] - loc[2*i]); err != nil {
+			return nil, err
+		}
+		groups[i] = String(s[loc[2*i]:loc[2*i+1]])
+	}
+	return NewList(groups...), nil
+}
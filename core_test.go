@@ -0,0 +1,285 @@
+package parens_test
+
+import (
+	"errors"
+	"fmt"
+	"math/big"
+	"testing"
+
+	"github.com/spy16/parens"
+)
+
+func TestCore_Meta(t *testing.T) {
+	t.Parallel()
+
+	table := []struct {
+		title   string
+		src     string
+		want    string
+		wantErr bool
+	}{
+		{
+			title: "NoMeta",
+			src:   `(meta (fn (x) x))`,
+			want:  "nil",
+		},
+		{
+			title: "NotAnnotated",
+			src:   `(meta 10)`,
+			want:  "nil",
+		},
+		{
+			title: "WithMeta",
+			src:   `(meta (with-meta (fn (x) x) (hash-map :pure true)))`,
+			want:  "{:pure true}",
+		},
+		{
+			title: "VaryMeta",
+			src:   `(meta (vary-meta (with-meta (hash-map) (hash-map :a 1)) assoc :b 2))`,
+			want:  "{:a 1, :b 2}",
+		},
+		{
+			title:   "WithMetaUnsupported",
+			src:     `(with-meta 10 (hash-map))`,
+			wantErr: true,
+		},
+		{
+			title:   "WithMetaNotMap",
+			src:     `(with-meta (hash-map) 10)`,
+			wantErr: true,
+		},
+		{
+			title: "Get",
+			src:   `(get (hash-map :a 1) :b :none)`,
+			want:  ":none",
+		},
+		{
+			title: "SymbolMeta",
+			src:   `(meta '^:private x)`,
+			want:  "{:private true}",
+		},
+		{
+			title: "ListMeta",
+			src:   `(meta '^{:doc "d"} ^String (a b))`,
+			want:  `{:doc "d", :tag String}`,
+		},
+		{
+			title: "AnnotatedSymbolEval",
+			src:   `((fn (^:num x) (hash-map :x x)) 1)`,
+			want:  "{:x 1}",
+		},
+		{
+			title: "CoreMeta",
+			src:   `(get (meta (var get)) :arglists)`,
+			want:  "((m k) (m k not-found))",
+		},
+	}
+
+	for _, tt := range table {
+		t.Run(tt.title, func(t *testing.T) {
+			got, err := parens.New(parens.WithCore()).Eval(readOne(t, tt.src))
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Eval() error = %#v, wantErr %#v", err, tt.wantErr)
+			} else if tt.wantErr {
+				return
+			}
+
+			s, err := got.SExpr()
+			requireNoErr(t, err)
+			assertEqual(t, tt.want, s)
+		})
+	}
+
+}
+
+func TestCore_Regex(t *testing.T) {
+	t.Parallel()
+
+	table := []struct {
+		title   string
+		src     string
+		want    string
+		wantErr bool
+	}{
+		{
+			title: "Find",
+			src:   `(re-find #"\d+" "ab12cd34")`,
+			want:  `"12"`,
+		},
+		{
+			title: "FindGroups",
+			src:   `(re-find #"(a)(x)?" "ba")`,
+			want:  `("a" "a" nil)`,
+		},
+		{
+			title: "FindNoMatch",
+			src:   `(re-find #"\d+" "abc")`,
+			want:  "nil",
+		},
+		{
+			title: "Matches",
+			src:   `(re-matches #"a|ab" "ab")`,
+			want:  `"ab"`,
+		},
+		{
+			title: "MatchesPartial",
+			src:   `(re-matches #"\d+" "12a")`,
+			want:  "nil",
+		},
+		{
+			title: "Seq",
+			src:   `(re-seq #"(\w)=(\d)" "a=1, b=2")`,
+			want:  `(("a=1" "a" "1") ("b=2" "b" "2"))`,
+		},
+		{
+			title: "SeqNoMatch",
+			src:   `(re-seq #"\d" "abc")`,
+			want:  "nil",
+		},
+		{
+			title:   "NotRegex",
+			src:     `(re-find "\\d" "1")`,
+			wantErr: true,
+		},
+		{
+			title: "FnLiteral",
+			src:   `(#(hash-map :a % :b %2) 1 2)`,
+			want:  "{:a 1, :b 2}",
+		},
+		{
+			title: "FnLiteralRest",
+			src:   `(#(hash-map :rest %&) 1 2)`,
+			want:  "{:rest (1 2)}",
+		},
+	}
+
+	for _, tt := range table {
+		t.Run(tt.title, func(t *testing.T) {
+			got, err := parens.New(parens.WithCore()).Eval(readOne(t, tt.src))
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Eval() error = %#v, wantErr %#v", err, tt.wantErr)
+			} else if tt.wantErr {
+				return
+			}
+
+			s, err := got.SExpr()
+			requireNoErr(t, err)
+			assertEqual(t, tt.want, s)
+		})
+	}
+
+}
+
+func TestCore_Numbers(t *testing.T) {
+	t.Parallel()
+
+	table := []struct {
+		title   string
+		src     string
+		want    string
+		wantErr bool
+	}{
+		{title: "AddNone", src: `(+)`, want: "0"},
+		{title: "Add", src: `(+ 1 2 3)`, want: "6"},
+		{title: "AddOverflow", src: `(+ 9223372036854775807 1)`, want: "9223372036854775808N"},
+		{title: "SubOverflow", src: `(- -9223372036854775808 1)`, want: "-9223372036854775809N"},
+		{title: "MulOverflow", src: `(* 4294967296 4294967296)`, want: "18446744073709551616N"},
+		{title: "Negate", src: `(- 5)`, want: "-5"},
+		{title: "NegateMin", src: `(- -9223372036854775808)`, want: "9223372036854775808N"},
+		{title: "BigIntContagion", src: `(+ 1N 1)`, want: "2N"},
+		{title: "DivExact", src: `(/ 6 3)`, want: "2"},
+		{title: "DivRatio", src: `(/ 6 4)`, want: "3/2"},
+		{title: "Reciprocal", src: `(/ 3)`, want: "1/3"},
+		{title: "RatioSum", src: `(+ 1/3 2/3)`, want: "1"},
+		{title: "RatioMul", src: `(* 1/3 3/4 2N)`, want: "1/2"},
+		{title: "BigIntDiv", src: `(/ 4N 2)`, want: "2N"},
+		{title: "DecimalAdd", src: `(+ 0.10M 0.2M)`, want: "0.30M"},
+		{title: "DecimalMul", src: `(* 1.50M 3)`, want: "4.50M"},
+		{title: "DecimalDiv", src: `(/ 1M 8)`, want: "0.125M"},
+		{title: "DecimalRatio", src: `(+ 1/4 1.0M)`, want: "1.25M"},
+		{title: "DecimalNonTerminating", src: `(/ 1M 3)`, wantErr: true},
+		{title: "FloatContagion", src: `(+ 1/2 0.25 1M)`, want: "1.75"},
+		{title: "DecimalFloatContagion", src: `(+ 0.1M 0.2)`, want: "0.30000000000000004"},
+		{title: "FloatDivZero", src: `(/ 1.0 0)`, want: "##Inf"},
+		{title: "DivByZero", src: `(/ 1 0)`, wantErr: true},
+		{title: "RatioDivByZero", src: `(/ 1/2 0)`, wantErr: true},
+		{title: "NotNumber", src: `(+ 1 "2")`, wantErr: true},
+		{title: "SubArity", src: `(-)`, wantErr: true},
+		{title: "NumEq", src: `(== 1 1.0 1N 2/2 1.00M)`, want: "true"},
+		{title: "NumNotEq", src: `(== 1/3 0.33M)`, want: "false"},
+		{title: "Less", src: `(< 1/3 0.34M 1 2N 2.5)`, want: "true"},
+		{title: "NotLess", src: `(< 1 1)`, want: "false"},
+		{title: "LessEq", src: `(<= 1 1N 1.0)`, want: "true"},
+		{title: "Greater", src: `(> 9223372036854775808 9223372036854775807)`, want: "true"},
+		{title: "GreaterEq", src: `(>= 2 3)`, want: "false"},
+		{title: "NaN", src: `(== ##NaN ##NaN)`, want: "false"},
+		{title: "CompareNotNumber", src: `(< 2 1 :a)`, wantErr: true},
+	}
+
+	for _, tt := range table {
+		t.Run(tt.title, func(t *testing.T) {
+			got, err := parens.New(parens.WithCore()).Eval(readOne(t, tt.src))
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Eval() error = %#v, wantErr %#v", err, tt.wantErr)
+			} else if tt.wantErr {
+				return
+			}
+
+			s, err := got.SExpr()
+			requireNoErr(t, err)
+			assertEqual(t, tt.want, s)
+		})
+	}
+
+	_, err := parens.New(parens.WithCore()).Eval(readOne(t, `(/ 1N 0)`))
+	if !errors.Is(err, parens.ErrArithmetic) {
+		t.Errorf("expecting ErrArithmetic, got %v", err)
+	}
+}
+
+func TestBigInt_Format(t *testing.T) {
+	t.Parallel()
+
+	n, _ := new(big.Int).SetString("18446744073709551616", 10)
+	bi := parens.BigInt{Int: n}
+
+	assertEqual(t, "18446744073709551616N", fmt.Sprintf("%v", bi))
+	assertEqual(t, "18446744073709551616N", fmt.Sprintf("%s", bi))
+	assertEqual(t, "18446744073709551616", fmt.Sprintf("%d", bi))
+	assertEqual(t, "10000000000000000", fmt.Sprintf("%x", bi))
+	assertEqual(t, "[18446744073709551616N]", fmt.Sprint([]parens.Any{bi}))
+}
+
+func TestCore_Backends(t *testing.T) {
+	t.Parallel()
+
+	table := []struct {
+		title   string
+		src     string
+		want    string
+		wantErr bool
+	}{
+		{title: "Meta", src: `(get (meta (with-meta (hash-map) {:k 1})) :k)`, want: "1"},
+		{title: "Assoc", src: `(get (assoc (hash-map) :a 1) :a)`, want: "1"},
+		{title: "Regex", src: `(re-find #"\d+" "abc123")`, want: `"123"`},
+		{title: "Numbers", src: `(+ 1/3 (* 2 1/3) 1N)`, want: "2N"},
+		{title: "Compare", src: `(< 1 (- 3 1) 2.5)`, want: "true"},
+		{title: "Arity", src: `(get 1)`, wantErr: true},
+		{title: "DivByZero", src: `(/ 1 0)`, wantErr: true},
+	}
+
+	for _, tt := range table {
+		t.Run(tt.title, func(t *testing.T) {
+			got, err := evalBackends(t, tt.src, parens.WithCore())
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Eval() error = %#v, wantErr %#v", err, tt.wantErr)
+			} else if tt.wantErr {
+				return
+			}
+
+			s, err := got.SExpr()
+			requireNoErr(t, err)
+			assertEqual(t, tt.want, s)
+		})
+	}
+}
@@ -0,0 +1,295 @@
+package parens
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+)
+
+// NewCoverage returns an empty Coverage.
+func NewCoverage() *Coverage {
+	return &Coverage{
+		forms:    map[Position]*int64{},
+		branches: map[branch]*int64{},
+	}
+}
+
+// Coverage records the number of times the position-annotated forms (See
+// reader.WithPositions()) on every line and both arms of every `if` form
+// are evaluated. Forms are registered with zero count when analyzed, so the
+// forms that were never evaluated are reported as well. Coverage is safe for
+// concurrent use. See WithCoverage().
+type Coverage struct {
+	mu       sync.Mutex
+	forms    map[Position]*int64 // keyed by file and line.
+	branches map[branch]*int64
+}
+
+// CoverageStats summarises the coverage.
+type CoverageStats struct {
+	Lines, LinesHit       int
+	Branches, BranchesHit int
+}
+
+type branch struct {
+	Position
+	arm int // 0 for then, 1 for else.
+}
+
+// Merge adds the counts recorded in other to c.
+func (c *Coverage) Merge(other *Coverage) {
+	other.mu.Lock()
+	forms := make(map[Position]int64, len(other.forms))
+	for pos, cnt := range other.forms {
+		forms[pos] = atomic.LoadInt64(cnt)
+	}
+	branches := make(map[branch]int64, len(other.branches))
+	for br, cnt := range other.branches {
+		branches[br] = atomic.LoadInt64(cnt)
+	}
+	other.mu.Unlock()
+
+	for pos, n := range forms {
+		atomic.AddInt64(c.form(pos), n)
+	}
+	for br, n := range branches {
+		atomic.AddInt64(c.branch(br), n)
+	}
+}
+
+// Stats returns the number of lines and branches found and hit.
+func (c *Coverage) Stats() CoverageStats {
+	var stats CoverageStats
+	for _, fc := range c.files() {
+		stats.Lines += len(fc.lines)
+		for _, hits := range fc.lines {
+			if hits > 0 {
+				stats.LinesHit++
+			}
+		}
+
+		stats.Branches += len(fc.branches)
+		for _, hits := range fc.branches {
+			if hits > 0 {
+				stats.BranchesHit++
+			}
+		}
+	}
+	return stats
+}
+
+// WriteLCOV writes the coverage in the LCOV tracefile format. The count of
+// a line is the total count of the forms starting on the line. Column of the
+// `if` form is used as the block number of its branches.
+func (c *Coverage) WriteLCOV(w io.Writer) error {
+	bw := bufio.NewWriter(w)
+	for _, fc := range c.files() {
+		fmt.Fprintf(bw, "TN:\nSF:%s\n", fc.file)
+
+		branchHits := 0
+		for _, br := range fc.branchKeys() {
+			taken := "-"
+			if hits := fc.branches[br]; hits > 0 {
+				taken = strconv.FormatInt(hits, 10)
+				branchHits++
+			}
+			fmt.Fprintf(bw, "BRDA:%d,%d,%d,%s\n", br.Ln, br.Col, br.arm, taken)
+		}
+		fmt.Fprintf(bw, "BRF:%d\nBRH:%d\n", len(fc.branches), branchHits)
+
+		lineHits := 0
+		for _, ln := range fc.lineKeys() {
+			if fc.lines[ln] > 0 {
+				lineHits++
+			}
+			fmt.Fprintf(bw, "DA:%d,%d\n", ln, fc.lines[ln])
+		}
+		fmt.Fprintf(bw, "LF:%d\nLH:%d\nend_of_record\n", len(fc.lines), lineHits)
+	}
+	return bw.Flush()
+}
+
+// ReadLCOV reads the coverage from a tracefile written by WriteLCOV(). This
+// can be used to merge the coverage of runs in different processes.
+func ReadLCOV(r io.Reader) (*Coverage, error) {
+	c := NewCoverage()
+	file := ""
+	sc := bufio.NewScanner(r)
+	for sc.Scan() {
+		line := strings.TrimSpace(sc.Text())
+		idx := strings.IndexByte(line, ':')
+		if idx < 0 {
+			continue
+		}
+
+		tag, fields := line[:idx], strings.Split(line[idx+1:], ",")
+		switch tag {
+		case "SF":
+			file = line[idx+1:]
+
+		case "DA":
+			if len(fields) < 2 {
+				return nil, fmt.Errorf("invalid lcov record: %s", line)
+			}
+			ln, err1 := strconv.Atoi(fields[0])
+			hits, err2 := strconv.ParseInt(fields[1], 10, 64)
+			if err1 != nil || err2 != nil {
+				return nil, fmt.Errorf("invalid lcov record: %s", line)
+			}
+			atomic.AddInt64(c.form(Position{File: file, Ln: ln}), hits)
+
+		case "BRDA":
+			if len(fields) != 4 {
+				return nil, fmt.Errorf("invalid lcov record: %s", line)
+			}
+			ln, err1 := strconv.Atoi(fields[0])
+			block, err2 := strconv.Atoi(fields[1])
+			arm, err3 := strconv.Atoi(fields[2])
+			if err1 != nil || err2 != nil || err3 != nil {
+				return nil, fmt.Errorf("invalid lcov record: %s", line)
+			}
+
+			var hits int64
+			if fields[3] != "-" {
+				var err error
+				if hits, err = strconv.ParseInt(fields[3], 10, 64); err != nil {
+					return nil, fmt.Errorf("invalid lcov record: %s", line)
+				}
+			}
+
+			br := branch{Position: Position{File: file, Ln: ln, Col: block}, arm: arm}
+			atomic.AddInt64(c.branch(br), hits)
+		}
+	}
+	return c, sc.Err()
+}
+
+func (c *Coverage) form(pos Position) *int64 {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	cnt, found := c.forms[pos]
+	if !found {
+		cnt = new(int64)
+		c.forms[pos] = cnt
+	}
+	return cnt
+}
+
+func (c *Coverage) branch(br branch) *int64 {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	cnt, found := c.branches[br]
+	if !found {
+		cnt = new(int64)
+		c.branches[br] = cnt
+	}
+	return cnt
+}
+
+type fileCoverage struct {
+	file     string
+	lines    map[int]int64
+	branches map[branch]int64
+}
+
+func (fc fileCoverage) lineKeys() []int {
+	keys := make([]int, 0, len(fc.lines))
+	for ln := range fc.lines {
+		keys = append(keys, ln)
+	}
+	sort.Ints(keys)
+	return keys
+}
+
+func (fc fileCoverage) branchKeys() []branch {
+	keys := make([]branch, 0, len(fc.branches))
+	for br := range fc.branches {
+		keys = append(keys, br)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		if keys[i].Ln != keys[j].Ln {
+			return keys[i].Ln < keys[j].Ln
+		} else if keys[i].Col != keys[j].Col {
+			return keys[i].Col < keys[j].Col
+		}
+		return keys[i].arm < keys[j].arm
+	})
+	return keys
+}
+
+// files returns the coverage grouped by file and line sorted by file name.
+func (c *Coverage) files() []fileCoverage {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	byFile := map[string]*fileCoverage{}
+	get := func(file string) *fileCoverage {
+		fc, found := byFile[file]
+		if !found {
+			fc = &fileCoverage{file: file, lines: map[int]int64{}, branches: map[branch]int64{}}
+			byFile[file] = fc
+		}
+		return fc
+	}
+
+	for pos, cnt := range c.forms {
+		get(pos.File).lines[pos.Ln] = atomic.LoadInt64(cnt)
+	}
+	for br, cnt := range c.branches {
+		get(br.File).branches[br] = atomic.LoadInt64(cnt)
+	}
+
+	files := make([]fileCoverage, 0, len(byFile))
+	for _, fc := range byFile {
+		files = append(files, *fc)
+	}
+	sort.Slice(files, func(i, j int) bool { return files[i].file < files[j].file })
+	return files
+}
+
+// cover wraps the expression analyzed from the form to record coverage if
+// the form is position-annotated.
+func (env *Env) cover(form Any, expr Expr) Expr {
+	if env.coverage == nil || expr == nil {
+		return expr
+	}
+
+	p, ok := form.(Positional)
+	if !ok || p.Pos().IsZero() {
+		return expr
+	}
+	pos := Position{File: p.Pos().File, Ln: p.Pos().Ln}
+	return &coverExpr{Expr: expr, count: env.coverage.form(pos)}
+}
+
+// coverBranch wraps the arm of the `if` form being analyzed to record its
+// coverage. Branches of forms without a position of their own (e.g., built
+// by macros) are not covered since they cannot be told apart.
+func (env *Env) coverBranch(arm int, expr Expr) Expr {
+	if env.coverage == nil || env.ownPos.IsZero() {
+		return expr
+	} else if expr == nil {
+		expr = &ConstExpr{Const: Nil{}}
+	}
+
+	br := branch{Position: env.ownPos, arm: arm}
+	return &coverExpr{Expr: expr, count: env.coverage.branch(br)}
+}
+
+// coverExpr counts the evaluations of the wrapped expression.
+type coverExpr struct {
+	Expr
+	count *int64
+}
+
+func (ce *coverExpr) Eval(env *Env) (Any, error) {
+	atomic.AddInt64(ce.count, 1)
+	return ce.Expr.Eval(env)
+}
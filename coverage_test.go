@@ -0,0 +1,126 @@
+package parens_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/spy16/parens"
+	"github.com/spy16/parens/reader"
+)
+
+const coverageSrc = `(def check
+  (fn (x)
+    (if x
+      (hash-map :ok x)
+      (hash-map :ok false))))
+(check true)
+(def unused (fn () (hash-map)))`
+
+func TestWithCoverage(t *testing.T) {
+	t.Parallel()
+
+	forEachBackend(t, func(t *testing.T, opts ...parens.Option) {
+		cov := parens.NewCoverage()
+		runCoverage(t, cov, opts...)
+
+		var out strings.Builder
+		requireNoErr(t, cov.WriteLCOV(&out))
+
+		want := strings.Join([]string{
+			"TN:",
+			"SF:rules.lisp",
+			"BRDA:3,5,0,1",
+			"BRDA:3,5,1,-",
+			"BRF:2",
+			"BRH:1",
+			"DA:1,1",
+			"DA:2,1",
+			"DA:3,1",
+			"DA:4,1",
+			"DA:5,0",
+			"DA:6,1",
+			"DA:7,2",
+			"LF:7",
+			"LH:6",
+			"end_of_record",
+		}, "\n") + "\n"
+		assertEqual(t, want, out.String())
+		assertEqual(t, parens.CoverageStats{Lines: 7, LinesHit: 6, Branches: 2, BranchesHit: 1}, cov.Stats())
+
+		t.Run("Merge", func(t *testing.T) {
+			other := parens.NewCoverage()
+			runCoverage(t, other, opts...)
+
+			read, err := parens.ReadLCOV(strings.NewReader(out.String()))
+			requireNoErr(t, err)
+
+			other.Merge(read)
+			assertEqual(t, parens.CoverageStats{Lines: 7, LinesHit: 6, Branches: 2, BranchesHit: 1}, other.Stats())
+
+			var merged strings.Builder
+			requireNoErr(t, other.WriteLCOV(&merged))
+			if !strings.Contains(merged.String(), "BRDA:3,5,0,2\n") || !strings.Contains(merged.String(), "DA:6,2\n") {
+				t.Errorf("counts not merged:\n%s", merged.String())
+			}
+		})
+	})
+}
+
+func runCoverage(t *testing.T, cov *parens.Coverage, opts ...parens.Option) {
+	rd := reader.New(strings.NewReader(coverageSrc), reader.WithPositions())
+	rd.File = "rules.lisp"
+	forms, err := rd.All()
+	requireNoErr(t, err)
+
+	env := parens.New(append(opts, parens.WithCore(), parens.WithCoverage(cov))...)
+	_, err = parens.EvalAll(env, forms)
+	requireNoErr(t, err)
+}
+
+func TestWithCoverage_NoPosition(t *testing.T) {
+	t.Parallel()
+
+	rd := reader.New(strings.NewReader("(def f\n  (fn (x)\n    (choose x)))\n(f true)"), reader.WithPositions())
+	rd.File = "rules.lisp"
+	forms, err := rd.All()
+	requireNoErr(t, err)
+
+	cov := parens.NewCoverage()
+	env := parens.New(parens.WithCore(), parens.WithCoverage(cov), parens.WithExpander(chooseExpander{}))
+	_, err = parens.EvalAll(env, forms)
+	requireNoErr(t, err)
+
+	// branches of the `if` built by the expander have no position.
+	assertEqual(t, 0, cov.Stats().Branches)
+}
+
+// chooseExpander expands `(choose x)` to `(hash-map :v (if x :a :b))` where
+// the `if` form is not Positional.
+type chooseExpander struct{}
+
+func (chooseExpander) Expand(_ *parens.Env, form parens.Any) (parens.Any, error) {
+	seq, ok := form.(parens.Seq)
+	if !ok {
+		return nil, nil
+	}
+
+	first, err := seq.First()
+	if err != nil || first == nil || !parens.Symbol("choose").Equals(first) {
+		return nil, err
+	}
+
+	next, err := seq.Next()
+	if err != nil {
+		return nil, err
+	}
+	arg, err := next.First()
+	if err != nil {
+		return nil, err
+	}
+
+	ife := plainSeq{parens.NewList(parens.Symbol("if"), arg, parens.Keyword("a"), parens.Keyword("b"))}
+	return parens.NewList(parens.Symbol("hash-map"), parens.Keyword("v"), ife), nil
+}
+
+// plainSeq hides all the methods of the Seq other than those of Seq.
+type plainSeq struct{ parens.Seq }
@@ -0,0 +1,308 @@
+package parens
+
+import (
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// Action is returned by the handler of a Debugger to resume the paused
+// evaluation.
+type Action int
+
+// Actions supported by the Debugger.
+const (
+	// Continue resumes the evaluation until the next breakpoint.
+	Continue Action = iota
+
+	// StepIn pauses at the next invocation.
+	StepIn
+
+	// StepOver pauses at the next invocation that is not made by the
+	// invocation being stepped over.
+	StepOver
+
+	// StepOut pauses at the next invocation after the function the paused
+	// invocation is made from returns.
+	StepOut
+
+	// Abort stops the evaluation with ErrAborted.
+	Abort
+)
+
+// NewDebugger returns a new Debugger that calls the handler every time the
+// evaluation pauses. If the handler is nil, all stops are continued.
+func NewDebugger(handler func(stop *Stop) Action) *Debugger {
+	return &Debugger{
+		handler: handler,
+		names:   map[string]bool{},
+		lines:   map[Position]bool{},
+	}
+}
+
+// Debugger pauses the evaluation before invocations matching a breakpoint
+// or while stepping and lets the handler inspect the paused evaluation.
+// Breakpoints are set on a function name (e.g., "println") or on the call
+// sites in a file and line (e.g., "main.lisp:12"). Call sites have positions
+// only if the forms were read with reader.WithPositions().
+//
+// Debugger is attached to an Env using WithDebugger() and is shared by the
+// envs forked from it. Only one evaluation is paused at a time: invocations
+// of other envs (e.g., from other goroutines) that should pause wait until
+// the paused evaluation resumes. Invocations made by the paused env or the
+// envs forked from it while the handler is running (e.g., by Stop.Eval())
+// are not paused.
+type Debugger struct {
+	handler func(stop *Stop) Action
+
+	// stop serializes the stops of concurrent evaluations.
+	stop sync.Mutex
+
+	mu    sync.Mutex
+	names map[string]bool
+	lines map[Position]bool // keyed by file and line.
+
+	// stepping state.
+	stepEnv   *Env
+	stepMode  Action
+	stepDepth int
+}
+
+// Stop describes an invocation at which the evaluation is paused. The stack
+// frame of the function making the invocation is the current frame of Env.
+type Stop struct {
+	Env   *Env
+	Name  string
+	Pos   Position
+	Args  []Any
+	Depth int // number of frames in the stack of Env.
+
+	// Breakpoint is the breakpoint that caused the stop. Empty if the stop
+	// is the result of stepping.
+	Breakpoint string
+}
+
+// Frame is a snapshot of a stack frame. See Env.Frames().
+type Frame struct {
+	Name   string
+	Pos    Position // position of the call site (if known).
+	Args   []Any
+	Locals map[string]Any
+}
+
+// SetHandler replaces the handler called when the evaluation pauses.
+func (d *Debugger) SetHandler(handler func(stop *Stop) Action) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.handler = handler
+}
+
+// SetBreakpoint sets a breakpoint on the function name or the "file:line".
+func (d *Debugger) SetBreakpoint(spec string) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if pos, ok := parseLineSpec(spec); ok {
+		d.lines[pos] = true
+	} else {
+		d.names[spec] = true
+	}
+}
+
+// ClearBreakpoint removes the breakpoint. Returns false if no such
+// breakpoint exists.
+func (d *Debugger) ClearBreakpoint(spec string) bool {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if pos, ok := parseLineSpec(spec); ok {
+		found := d.lines[pos]
+		delete(d.lines, pos)
+		return found
+	}
+	found := d.names[spec]
+	delete(d.names, spec)
+	return found
+}
+
+// Breakpoints returns all the breakpoints sorted.
+func (d *Debugger) Breakpoints() []string {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	specs := make([]string, 0, len(d.names)+len(d.lines))
+	for name := range d.names {
+		specs = append(specs, name)
+	}
+	for pos := range d.lines {
+		specs = append(specs, pos.File+":"+strconv.Itoa(pos.Ln))
+	}
+	sort.Strings(specs)
+	return specs
+}
+
+// check pauses the evaluation if the invocation about to be made matches a
+// breakpoint or the stepping state.
+func (d *Debugger) check(env *Env, name string, pos Position, args []Any) error {
+	if env.debugging {
+		// invoked by the handler of the stop.
+		return nil
+	} else if d.stopAt(env, name, pos) == nil {
+		return nil
+	}
+
+	d.stop.Lock()
+	defer d.stop.Unlock()
+
+	// state may have changed while waiting for the other stops.
+	bp := d.stopAt(env, name, pos)
+	if bp == nil {
+		return nil
+	}
+
+	d.mu.Lock()
+	handler := d.handler
+	d.mu.Unlock()
+
+	depth := len(env.stack)
+	action := Continue
+	if handler != nil {
+		env.debugging = true
+		defer func() { env.debugging = false }()
+
+		action = handler(&Stop{
+			Env:        env,
+			Name:       name,
+			Pos:        pos,
+			Args:       args,
+			Depth:      depth,
+			Breakpoint: *bp,
+		})
+	}
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	d.stepEnv, d.stepMode, d.stepDepth = nil, Continue, 0
+	switch action {
+	case StepIn, StepOver, StepOut:
+		d.stepEnv, d.stepMode, d.stepDepth = env, action, depth
+
+	case Abort:
+		return Error{
+			Cause:   ErrAborted,
+			Message: name,
+		}
+	}
+	return nil
+}
+
+// stopAt returns the breakpoint (empty if stepping) at which the invocation
+// should pause. Returns nil if it should not pause.
+func (d *Debugger) stopAt(env *Env, name string, pos Position) *string {
+	depth := len(env.stack)
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	bp := d.breakpoint(name, pos)
+	stepping := d.stepEnv == env && d.stepMode != Continue &&
+		(d.stepMode == StepIn ||
+			(d.stepMode == StepOver && depth <= d.stepDepth) ||
+			(d.stepMode == StepOut && depth < d.stepDepth))
+	if bp == "" && !stepping {
+		return nil
+	}
+	return &bp
+}
+
+func (d *Debugger) breakpoint(name string, pos Position) string {
+	if d.names[name] {
+		return name
+	}
+
+	line := Position{File: pos.File, Ln: pos.Ln}
+	if !pos.IsZero() && d.lines[line] {
+		return line.File + ":" + strconv.Itoa(line.Ln)
+	}
+	return ""
+}
+
+// Frames returns a snapshot of the stack frames of the stop innermost first.
+func (s *Stop) Frames() []Frame { return s.Env.Frames() }
+
+// Eval evaluates the form in the paused stack frame. Locals of the frame can
+// be referred by their names.
+func (s *Stop) Eval(form Any) (Any, error) {
+	env := s.Env
+	if len(env.stack) == 0 {
+		return env.Eval(form)
+	}
+
+	top := env.stack[len(env.stack)-1]
+	fs, pop := env.pushFrame()
+	for slot, name := range top.names {
+		if slot < len(top.Locals) {
+			fs.alloc(name)
+			fs.root.names[name] = slot
+		}
+	}
+	for len(fs.slots) < len(top.Locals) {
+		fs.alloc("")
+	}
+	expr, err := env.Analyze(form)
+	pop()
+	if err != nil {
+		return nil, err
+	} else if expr == nil {
+		return Nil{}, nil
+	}
+
+	// evaluate in a copy of the frame with room for the locals declared by
+	// the form.
+	frame := top
+	frame.Locals = make([]Any, len(fs.slots))
+	copy(frame.Locals, top.Locals)
+	frame.names = fs.slots
+	env.push(frame)
+	defer env.pop()
+
+	return expr.Eval(env)
+}
+
+// Frames returns a snapshot of the stack frames of the env innermost first.
+// Locals that are not assigned yet are not included.
+func (env *Env) Frames() []Frame {
+	frames := make([]Frame, 0, len(env.stack))
+	for i := len(env.stack) - 1; i >= 0; i-- {
+		sf := env.stack[i]
+		f := Frame{
+			Name:   sf.Name,
+			Pos:    sf.Pos,
+			Args:   append([]Any(nil), sf.Args...),
+			Locals: map[string]Any{},
+		}
+		for slot, name := range sf.names {
+			if slot < len(sf.Locals) && sf.Locals[slot] != nil && name != "" {
+				f.Locals[name] = sf.Locals[slot]
+			}
+		}
+		frames = append(frames, f)
+	}
+	return frames
+}
+
+// parseLineSpec parses the "file:line" breakpoint spec.
+func parseLineSpec(spec string) (Position, bool) {
+	idx := strings.LastIndexByte(spec, ':')
+	if idx <= 0 {
+		return Position{}, false
+	}
+
+	ln, err := strconv.Atoi(spec[idx+1:])
+	if err != nil || ln <= 0 {
+		return Position{}, false
+	}
+	return Position{File: spec[:idx], Ln: ln}, true
+}
@@ -0,0 +1,146 @@
+package parens_test
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/spy16/parens"
+	"github.com/spy16/parens/reader"
+)
+
+const debugSrc = `(def inner (fn (x) (hash-map :x x)))
+(def outer (fn (a b)
+  (let (c (inner a))
+    (inner b))))
+(outer 1 2)
+(inner 3)`
+
+func TestDebugger(t *testing.T) {
+	t.Parallel()
+
+	forEachBackend(t, func(t *testing.T, opts ...parens.Option) {
+		t.Run("Stepping", func(t *testing.T) {
+			var stops []string
+			actions := []parens.Action{parens.StepOver, parens.StepIn, parens.StepOut, parens.Continue}
+			d := parens.NewDebugger(func(stop *parens.Stop) parens.Action {
+				stops = append(stops, fmt.Sprintf("%s@%d %d [%s]", stop.Name, stop.Pos.Ln, stop.Depth, stop.Breakpoint))
+				action := actions[0]
+				actions = actions[1:]
+				return action
+			})
+			d.SetBreakpoint("inner")
+
+			requireNoErr(t, runDebug(t, d, opts...))
+			assertEqual(t, []string{
+				"inner@3 1 [inner]",
+				"inner@4 1 [inner]",
+				"hash-map@1 2 []",
+				"inner@6 0 [inner]",
+			}, stops)
+		})
+
+		t.Run("Inspect", func(t *testing.T) {
+			var locals []map[string]parens.Any
+			var evals []parens.Any
+			d := parens.NewDebugger(func(stop *parens.Stop) parens.Action {
+				if stop.Depth > 0 {
+					locals = append(locals, stop.Frames()[0].Locals)
+					v, err := stop.Eval(readOne(t, `(let (d (hash-map :b b)) d)`))
+					requireNoErr(t, err)
+					evals = append(evals, v)
+				}
+				return parens.Continue
+			})
+			d.SetBreakpoint("debug.lisp:4")
+
+			requireNoErr(t, runDebug(t, d, opts...))
+			assertEqual(t, 1, len(locals))
+			assertEqual(t, parens.Int64(1), locals[0]["a"])
+			assertEqual(t, parens.Int64(2), locals[0]["b"])
+			assertEqual(t, 1, len(evals))
+			s, err := evals[0].SExpr()
+			requireNoErr(t, err)
+			assertEqual(t, "{:b 2}", s)
+		})
+
+		t.Run("Abort", func(t *testing.T) {
+			d := parens.NewDebugger(func(stop *parens.Stop) parens.Action { return parens.Abort })
+			d.SetBreakpoint("hash-map")
+
+			err := runDebug(t, d, opts...)
+			if !errors.Is(err, parens.ErrAborted) {
+				t.Errorf("expecting ErrAborted, got %v", err)
+			}
+		})
+	})
+}
+
+func TestDebugger_Concurrent(t *testing.T) {
+	t.Parallel()
+
+	var stops, paused int32
+	d := parens.NewDebugger(func(stop *parens.Stop) parens.Action {
+		if atomic.AddInt32(&paused, 1) > 1 {
+			t.Errorf("more than one evaluation paused")
+		}
+		atomic.AddInt32(&stops, 1)
+
+		// invocations made by the handler are not paused.
+		if _, err := stop.Eval(parens.NewList(parens.Symbol("inner"), parens.Keyword("nested"))); err != nil {
+			t.Errorf("unexpected error: %v", err)
+		}
+
+		time.Sleep(time.Millisecond)
+		atomic.AddInt32(&paused, -1)
+		return parens.Continue
+	})
+	d.SetBreakpoint("inner")
+
+	env := parens.New(parens.WithCore(), parens.WithDebugger(d))
+	_, err := env.Eval(readOne(t, `(def inner (fn (x) (hash-map :x x)))`))
+	requireNoErr(t, err)
+
+	form := readOne(t, `(inner 1)`)
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if _, err := env.Fork().Eval(form); err != nil {
+				t.Errorf("unexpected error: %v", err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	assertEqual(t, int32(10), atomic.LoadInt32(&stops))
+}
+
+func TestDebugger_Breakpoints(t *testing.T) {
+	t.Parallel()
+
+	d := parens.NewDebugger(nil)
+	d.SetBreakpoint("main.lisp:12")
+	d.SetBreakpoint("println")
+	assertEqual(t, []string{"main.lisp:12", "println"}, d.Breakpoints())
+
+	assertEqual(t, true, d.ClearBreakpoint("main.lisp:12"))
+	assertEqual(t, false, d.ClearBreakpoint("main.lisp:12"))
+	assertEqual(t, []string{"println"}, d.Breakpoints())
+}
+
+func runDebug(t *testing.T, d *parens.Debugger, opts ...parens.Option) error {
+	rd := reader.New(strings.NewReader(debugSrc), reader.WithPositions())
+	rd.File = "debug.lisp"
+	forms, err := rd.All()
+	requireNoErr(t, err)
+
+	env := parens.New(append(opts, parens.WithCore(), parens.WithDebugger(d))...)
+	_, err = parens.EvalAll(env, forms)
+	return err
+}
@@ -0,0 +1,206 @@
+package parens
+
+import (
+	"fmt"
+	"math/rand"
+	"reflect"
+	"sync"
+	"time"
+)
+
+// Deterministic configures the deterministic execution mode of an Env. See
+// WithDeterministic().
+type Deterministic struct {
+	// Seed is used to seed the random number generator of the Env.
+	Seed int64
+
+	// Now is the time returned by the clock of the Env.
+	Now time.Time
+
+	// Journal, if not nil, records the calls into native Go functions or
+	// replays the calls recorded earlier.
+	Journal *Journal
+}
+
+// Call is a call into a native Go function recorded in a Journal. Name, Args
+// and Result are forms, hence calls can be saved as s-expressions and read
+// back to be replayed later (See NewReplayJournal()).
+type Call struct {
+	Name   string
+	Args   []Any
+	Result Any
+	Err    error
+}
+
+// NewJournal returns an empty Journal that records calls.
+func NewJournal() *Journal { return &Journal{} }
+
+// NewReplayJournal returns a Journal that replays the calls (e.g., recorded
+// by an earlier process). See Journal.Replay().
+func NewReplayJournal(calls []Call) *Journal {
+	return &Journal{calls: append([]Call(nil), calls...), replay: true}
+}
+
+// Journal records the calls made into native Go functions during the
+// deterministic evaluation. Calls into the functions of the core library
+// are not recorded since they are deterministic. Journal is safe for
+// concurrent use.
+type Journal struct {
+	mu     sync.Mutex
+	calls  []Call
+	replay bool
+	next   int
+	depth  int // depth of nested calls made by a native function.
+}
+
+// Calls returns the calls recorded in the journal.
+func (j *Journal) Calls() []Call {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	return append([]Call(nil), j.calls...)
+}
+
+// Replay returns a Journal that replays the calls recorded in j. When an
+// Env replays a Journal, calls into native Go functions are not made and the
+// recorded results are returned instead. Calls are verified to be made in
+// the recorded order with the recorded arguments.
+func (j *Journal) Replay() *Journal {
+	return NewReplayJournal(j.Calls())
+}
+
+// Done returns an error if the journal is replaying and not all the calls
+// recorded were replayed.
+func (j *Journal) Done() error {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	if j.replay && j.next < len(j.calls) {
+		return Error{
+			Cause:   ErrReplayMismatch,
+			Message: fmt.Sprintf("%d recorded calls not replayed", len(j.calls)-j.next),
+		}
+	}
+	return nil
+}
+
+func (j *Journal) call(env *Env, name string, fn Invokable, args []Any) (Any, error) {
+	j.mu.Lock()
+	if !j.replay || j.depth > 0 {
+		// calls made by a native function are not recorded since they are
+		// not made when the outer call is replayed.
+		nested := j.depth > 0
+		j.depth++
+		j.mu.Unlock()
+
+		res, err := fn.Invoke(env, args...)
+
+		j.mu.Lock()
+		defer j.mu.Unlock()
+		j.depth--
+		if !nested {
+			j.calls = append(j.calls, Call{
+				Name:   name,
+				Args:   append([]Any(nil), args...),
+				Result: res,
+				Err:    err,
+			})
+		}
+		return res, err
+	}
+	defer j.mu.Unlock()
+
+	if j.next >= len(j.calls) {
+		return nil, Error{
+			Cause:   ErrReplayMismatch,
+			Message: fmt.Sprintf("unexpected call to '%s'", name),
+		}
+	}
+
+	c := j.calls[j.next]
+	if c.Name != name || !sameForms(c.Args, args) {
+		return nil, Error{
+			Cause:   ErrReplayMismatch,
+			Message: fmt.Sprintf("call %d: expected '%s' with %v, got '%s' with %v", j.next, c.Name, c.Args, name, args),
+		}
+	}
+	j.next++
+	return c.Result, c.Err
+}
+
+// Rand returns the random number generator of the env. In deterministic
+// mode, the generator is seeded with the configured seed and is shared by
+// the forks of the env. The generator is safe for concurrent use.
+func (env *Env) Rand() *rand.Rand {
+	if env.det != nil {
+		return env.det.rand
+	}
+	return defaultRand
+}
+
+// Now returns the current time as seen by the env. In deterministic mode,
+// the configured fixed time is returned.
+func (env *Env) Now() time.Time {
+	if env.det != nil {
+		return env.det.now
+	}
+	return time.Now()
+}
+
+type determinism struct {
+	rand    *rand.Rand
+	now     time.Time
+	journal *Journal
+}
+
+var (
+	defaultRand = rand.New(&lockedSource{src: rand.NewSource(time.Now().UnixNano())})
+
+	// corePtrs is the set of code pointers of the core library functions.
+	corePtrs = map[uintptr]bool{}
+)
+
+func init() {
+	for _, c := range core {
+		corePtrs[reflect.ValueOf(c.fn).Pointer()] = true
+	}
+}
+
+// journaled returns true if the calls into the target must be recorded.
+func journaled(target Invokable) bool {
+	switch fn := target.(type) {
+	case *Fn:
+		return false
+	case GoFunc:
+		return !corePtrs[reflect.ValueOf(fn).Pointer()]
+	}
+	return true
+}
+
+func sameForms(a, b []Any) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if formString(a[i]) != formString(b[i]) {
+			return false
+		}
+	}
+	return true
+}
+
+type lockedSource struct {
+	mu  sync.Mutex
+	src rand.Source
+}
+
+func (ls *lockedSource) Int63() int64 {
+	ls.mu.Lock()
+	defer ls.mu.Unlock()
+	return ls.src.Int63()
+}
+
+func (ls *lockedSource) Seed(seed int64) {
+	ls.mu.Lock()
+	defer ls.mu.Unlock()
+	ls.src.Seed(seed)
+}
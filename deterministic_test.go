@@ -0,0 +1,159 @@
+package parens_test
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/spy16/parens"
+	"github.com/spy16/parens/reader"
+)
+
+func TestWithDeterministic(t *testing.T) {
+	t.Parallel()
+
+	clock := time.Date(2020, 9, 9, 0, 0, 0, 0, time.UTC)
+
+	newEnv := func(journal *parens.Journal, fetch parens.GoFunc) *parens.Env {
+		return parens.New(parens.WithCore(),
+			parens.WithDeterministic(parens.Deterministic{Seed: 42, Now: clock, Journal: journal}),
+			parens.WithGlobals(map[string]parens.Any{"fetch": fetch}, nil),
+		)
+	}
+
+	t.Run("RandAndClock", func(t *testing.T) {
+		src := `(hash-map :r (rand-int 1000000) :f (rand) :now (now))`
+
+		first, err := newEnv(nil, nil).Eval(readOne(t, src))
+		requireNoErr(t, err)
+		second, err := evalBackends(t, src, parens.WithCore(),
+			parens.WithDeterministic(parens.Deterministic{Seed: 42, Now: clock}))
+		requireNoErr(t, err)
+		assertEqual(t, first, second)
+
+		now, _ := first.(parens.Map).EntryAt(parens.Keyword("now"))
+		assertEqual(t, parens.Int64(clock.UnixNano()/int64(time.Millisecond)), now)
+	})
+
+	t.Run("SerialGo", func(t *testing.T) {
+		env := newEnv(nil, nil)
+		_, err := env.Eval(readOne(t, `(go (def spawned :done))`))
+		requireNoErr(t, err)
+
+		res, err := env.Eval(parens.Symbol("spawned"))
+		requireNoErr(t, err)
+		assertEqual(t, parens.Keyword("done"), res)
+	})
+
+	t.Run("RecordReplay", func(t *testing.T) {
+		calls := 0
+		fetch := parens.GoFunc(func(_ *parens.Env, args ...parens.Any) (parens.Any, error) {
+			calls++
+			return parens.Int64(time.Now().UnixNano()), nil
+		})
+		src := readOne(t, `(hash-map :a (fetch 1) :b (fetch 2) :r (rand-int 100))`)
+
+		journal := parens.NewJournal()
+		recorded, err := newEnv(journal, fetch).Eval(src)
+		requireNoErr(t, err)
+		assertEqual(t, 2, calls)
+		assertEqual(t, 2, len(journal.Calls()))
+
+		replay := journal.Replay()
+		replayed, err := newEnv(replay, fetch).Eval(src)
+		requireNoErr(t, err)
+		requireNoErr(t, replay.Done())
+		assertEqual(t, 2, calls)
+
+		want, _ := recorded.SExpr()
+		got, _ := replayed.SExpr()
+		assertEqual(t, want, got)
+	})
+
+	t.Run("ReplayOnBytecode", func(t *testing.T) {
+		fetch := parens.GoFunc(func(_ *parens.Env, args ...parens.Any) (parens.Any, error) {
+			return parens.Int64(time.Now().UnixNano()), nil
+		})
+		src := readOne(t, `((fn (x) (hash-map :x (fetch x) :r (rand-int 100))) 1)`)
+
+		journal := parens.NewJournal()
+		recorded, err := newEnv(journal, fetch).Eval(src)
+		requireNoErr(t, err)
+
+		replay := journal.Replay()
+		replayed, err := parens.New(parens.WithAnalyzer(parens.NewCompiler(nil)), parens.WithCore(),
+			parens.WithDeterministic(parens.Deterministic{Seed: 42, Now: clock, Journal: replay}),
+			parens.WithGlobals(map[string]parens.Any{"fetch": fetch}, nil),
+		).Eval(src)
+		requireNoErr(t, err)
+		requireNoErr(t, replay.Done())
+		assertEqual(t, recorded, replayed)
+	})
+
+	t.Run("ReplaySaved", func(t *testing.T) {
+		fetch := parens.GoFunc(func(_ *parens.Env, args ...parens.Any) (parens.Any, error) {
+			return parens.NewList(args[0], parens.Int64(time.Now().UnixNano())), nil
+		})
+		src := readOne(t, `(hash-map :a (fetch :x) :b (fetch "y"))`)
+
+		journal := parens.NewJournal()
+		recorded, err := newEnv(journal, fetch).Eval(src)
+		requireNoErr(t, err)
+
+		// save the calls as s-expressions and read them back.
+		var saved strings.Builder
+		for _, c := range journal.Calls() {
+			saved.WriteString(sexpr(t, parens.NewList(parens.Symbol(c.Name), parens.NewList(c.Args...), c.Result)))
+			saved.WriteString("\n")
+		}
+
+		forms, err := reader.New(strings.NewReader(saved.String())).All()
+		requireNoErr(t, err)
+
+		var calls []parens.Call
+		for _, form := range forms {
+			items := seqItems(t, form)
+			calls = append(calls, parens.Call{
+				Name:   string(items[0].(parens.Symbol)),
+				Args:   seqItems(t, items[1]),
+				Result: items[2],
+			})
+		}
+
+		replay := parens.NewReplayJournal(calls)
+		replayed, err := newEnv(replay, fetch).Eval(src)
+		requireNoErr(t, err)
+		requireNoErr(t, replay.Done())
+		assertEqual(t, sexpr(t, recorded), sexpr(t, replayed))
+	})
+
+	t.Run("ReplayMismatch", func(t *testing.T) {
+		fetch := parens.GoFunc(func(_ *parens.Env, args ...parens.Any) (parens.Any, error) {
+			return args[0], nil
+		})
+
+		journal := parens.NewJournal()
+		_, err := newEnv(journal, fetch).Eval(readOne(t, `(fetch 1)`))
+		requireNoErr(t, err)
+
+		_, err = newEnv(journal.Replay(), fetch).Eval(readOne(t, `(fetch 2)`))
+		if !errors.Is(err, parens.ErrReplayMismatch) {
+			t.Errorf("expecting ErrReplayMismatch, got %v", err)
+		}
+
+		if err := journal.Replay().Done(); !errors.Is(err, parens.ErrReplayMismatch) {
+			t.Errorf("expecting ErrReplayMismatch, got %v", err)
+		}
+	})
+
+}
+
+func seqItems(t *testing.T, seq parens.Any) []parens.Any {
+	var items []parens.Any
+	requireNoErr(t, parens.ForEach(seq.(parens.Seq), func(item parens.Any) (bool, error) {
+		items = append(items, item)
+		return false, nil
+	}))
+	return items
+}
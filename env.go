@@ -2,24 +2,51 @@ package parens
 
 import (
 	"context"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
 	"sync"
+	"sync/atomic"
+	"time"
 )
 
-var _ ConcurrentMap = (*mutexMap)(nil)
+var (
+	_ ConcurrentMap = (*mutexMap)(nil)
+	_ ConcurrentMap = (*cowMap)(nil)
+)
 
 // Env represents the environment/context in which forms are evaluated
-// for result. Env is not safe for concurrent use. Use fork() to get a
+// for result. Env is not safe for concurrent use. Use Fork() to get a
 // child context for concurrent executions.
 type Env struct {
 	ctx      context.Context
 	analyzer Analyzer
 	expander Expander
 	globals  ConcurrentMap
+	bindings *bindingFrame
 	stack    []stackFrame
+	vals     []Any // operands of the bytecode VM and locals of Fn frames.
 	maxDepth int
+	limits   *limiter
+	policies []Policy
+	det      *determinism
+	tracer   Tracer
+	coverage *Coverage
+	debugger *Debugger
+
+	// set while the env is paused by the debugger.
+	debugging bool
+
+	// analysis state.
+	scope   *scope
+	pending []string
+	pos     Position // of the form being analyzed or an enclosing form.
+	ownPos  Position // of the form being analyzed, zero if it has none.
 }
 
 // ConcurrentMap is used by the Env to store variables in the global stack frame.
+// Env stores *Var values in the map.
 type ConcurrentMap interface {
 	// Store should store the key-value pair in the map.
 	Store(key string, val Any)
@@ -28,6 +55,15 @@ type ConcurrentMap interface {
 	// Returns nil, false otherwise.
 	Load(key string) (Any, bool)
 
+	// Delete should remove the key and associated value from the map. It
+	// should be a no-op if the key does not exist.
+	Delete(key string)
+
+	// Range should call fn for each key-value pair in the map until fn
+	// returns false. Range need not correspond to a consistent snapshot
+	// of the map if it is modified concurrently.
+	Range(fn func(key string, val Any) bool)
+
 	// Map should return a native Go map of all key-values in the concurrent
 	// map. This can be used for iteration etc.
 	Map() map[string]Any
@@ -36,7 +72,7 @@ type ConcurrentMap interface {
 // Eval performs macro-expansion if necessary, converts the expanded form
 // to an expression and evaluates the resulting expression.
 func (env *Env) Eval(form Any) (Any, error) {
-	expr, err := env.expandAnalyze(form)
+	expr, err := env.Analyze(form)
 	if err != nil {
 		return nil, err
 	} else if expr == nil {
@@ -46,7 +82,10 @@ func (env *Env) Eval(form Any) (Any, error) {
 	return expr.Eval(env)
 }
 
-func (env *Env) expandAnalyze(form Any) (Expr, error) {
+// Analyze performs macro-expansion if necessary and converts the form to an
+// expression that can be evaluated against an Env. Analysis has no effect on
+// the global bindings.
+func (env *Env) Analyze(form Any) (Expr, error) {
 	if expr, ok := form.(Expr); ok {
 		// Already an Expr, nothing to do.
 		return expr, nil
@@ -55,30 +94,166 @@ func (env *Env) expandAnalyze(form Any) (Expr, error) {
 	if expanded, err := env.expander.Expand(env, form); err != nil {
 		return nil, err
 	} else if expanded != nil {
+		if env.tracer != nil {
+			env.tracer.Expanded(env, form, expanded)
+		}
+
 		// Expansion did happen. Throw away the old form and continue with
-		// the expanded version.
-		form = expanded
+		// the expanded version carrying the metadata of the old form.
+		if form, err = carryMeta(form, expanded); err != nil {
+			return nil, err
+		}
 	}
 
-	return env.analyzer.Analyze(env, form)
+	expr, err := env.analyzer.Analyze(env, form)
+	if env.tracer != nil {
+		env.tracer.Analyzed(env, form, expr, err)
+	}
+	return expr, err
 }
 
-// fork creates a child context from Env and returns it. The child context
-// can be used as context for an independent thread of execution.
-func (env *Env) fork() *Env {
+// Compile analyzes the form once and returns a Program that can be run many
+// times. Compile is not safe for concurrent use with other operations on
+// env. See Program.Run().
+func (env *Env) Compile(form Any) (Program, error) {
+	expr, err := env.Analyze(form)
+	if err != nil {
+		return Program{}, err
+	} else if expr == nil {
+		expr = &ConstExpr{Const: Nil{}}
+	}
+	return Program{expr: expr}, nil
+}
+
+// Fork creates a child context from Env and returns it. The child context
+// can be used as context for an independent thread of execution. Dynamic
+// bindings active in env at the time of fork are visible in the child, but
+// bindings established later in either of them are not shared.
+func (env *Env) Fork() *Env {
 	return &Env{
 		ctx:      env.ctx,
 		globals:  env.globals,
+		bindings: env.bindings,
 		expander: env.expander,
 		analyzer: env.analyzer,
 		maxDepth: env.maxDepth,
+		limits:   env.limits,
+		policies: env.policies,
+		det:      env.det,
+		tracer:   env.tracer,
+		coverage: env.coverage,
+		debugger: env.debugger,
+
+		// forks made while paused (e.g., by `go` forms of Stop.Eval()) must
+		// not wait for the stop they are part of.
+		debugging: env.debugging,
+	}
+}
+
+// Bind establishes thread-local bindings for the given dynamic vars in env
+// and returns a function that restores the previous bindings. Bindings are
+// visible to env and to the envs forked from it after the call. Every name
+// must refer to an existing dynamic var (See Var.Dynamic()).
+func (env *Env) Bind(vals map[string]Any) (restore func(), err error) {
+	for name := range vals {
+		if err := env.checkGlobal(name); err != nil {
+			return nil, err
+		}
+
+		v, found := env.Var(name)
+		if !found {
+			return nil, Error{
+				Cause:   ErrNotFound,
+				Message: name,
+			}
+		} else if !v.Dynamic() {
+			return nil, Error{
+				Cause:   ErrNotDynamic,
+				Message: name,
+			}
+		}
+	}
+
+	prev := env.bindings
+	env.bindings = &bindingFrame{vals: vals, prev: prev}
+	return func() { env.bindings = prev }, nil
+}
+
+// invoke calls the target with the arguments in a new stack frame. pos is
+// the position of the call site (if known).
+func (env *Env) invoke(name string, pos Position, target Any, args []Any) (Any, error) {
+	if env.debugger != nil {
+		if err := env.debugger.check(env, name, pos, args); err != nil {
+			return nil, err
+		}
+	}
+
+	if env.tracer == nil {
+		return env.doInvoke(name, pos, target, args)
+	}
+
+	env.tracer.InvokeStart(env, name, args)
+	start := time.Now()
+	res, err := env.doInvoke(name, pos, target, args)
+	env.tracer.InvokeEnd(env, name, args, res, err, time.Since(start))
+	return res, err
+}
+
+func (env *Env) doInvoke(name string, pos Position, target Any, args []Any) (Any, error) {
+	fn, ok := target.(Invokable)
+	if !ok {
+		return nil, Error{
+			Cause:   ErrNotInvokable,
+			Message: fmt.Sprintf("value of type '%s' is not invokable", reflect.TypeOf(target)),
+		}
+	}
+
+	if len(env.stack) >= env.maxDepth && env.maxDepth > 0 {
+		return nil, limitErr("max depth", env.maxDepth)
+	} else if err := env.step(); err != nil {
+		return nil, err
 	}
+
+	env.push(stackFrame{
+		Name: name,
+		Pos:  pos,
+		Args: args,
+	})
+	defer env.pop()
+
+	var res Any
+	var err error
+	if env.det != nil && env.det.journal != nil && journaled(fn) {
+		res, err = env.det.journal.call(env, name, fn, args)
+	} else {
+		res, err = fn.Invoke(env, args...)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return res, env.checkResult(res)
 }
 
 func (env *Env) push(frame stackFrame) {
 	env.stack = append(env.stack, frame)
 }
 
+// alloca reserves n slots on the value stack of the env and returns them.
+// The slots must be released in reverse order of allocation using release().
+func (env *Env) alloca(n int) []Any {
+	base := len(env.vals)
+	env.vals = append(env.vals, make([]Any, n)...)
+	return env.vals[base : base+n : base+n]
+}
+
+// release clears and removes the slots of the value stack above base.
+func (env *Env) release(base int) {
+	for i := base; i < len(env.vals); i++ {
+		env.vals[i] = nil // allow the values to be garbage collected.
+	}
+	env.vals = env.vals[:base]
+}
+
 func (env *Env) pop() (frame *stackFrame) {
 	if len(env.stack) == 0 {
 		panic("pop from empty stack")
@@ -87,27 +262,162 @@ func (env *Env) pop() (frame *stackFrame) {
 	return frame
 }
 
-func (env *Env) setGlobal(key string, value Any) {
-	env.globals.Store(key, value)
+// Var returns the global Var bound to the name if it exists and is permitted
+// by the policies of the env.
+func (env *Env) Var(name string) (*Var, bool) {
+	if env.checkGlobal(name) != nil {
+		return nil, false
+	}
+
+	v, found := env.globals.Load(name)
+	if !found {
+		return nil, false
+	}
+	gv, ok := v.(*Var)
+	return gv, ok
 }
 
-func (env Env) resolve(sym string) Any {
-	if len(env.stack) > 0 {
-		// check inside top of the stack for local bindings.
-		top := env.stack[len(env.stack)-1]
-		if v, found := top.Vars[sym]; found {
-			return v
+// Globals returns the global Vars whose names start with the prefix, sorted
+// by the name. An empty prefix returns all the globals.
+func (env *Env) Globals(prefix string) []*Var {
+	var vars []*Var
+	env.globals.Range(func(key string, val Any) bool {
+		if v, ok := val.(*Var); ok && strings.HasPrefix(key, prefix) && env.checkGlobal(key) == nil {
+			vars = append(vars, v)
 		}
+		return true
+	})
+
+	sort.Slice(vars, func(i, j int) bool {
+		return vars[i].Name() < vars[j].Name()
+	})
+	return vars
+}
+
+// Undef removes the global binding for the name. Returns false if no such
+// binding exists. Expressions that were analyzed before the removal are
+// not affected.
+func (env *Env) Undef(name string) bool {
+	if _, found := env.Var(name); !found {
+		return false
 	}
-	// return the value from global bindings if found.
-	v, _ := env.globals.Load(sym)
+	env.globals.Delete(name)
+	return true
+}
+
+// define binds the value to the name in the global bindings. If a Var with
+// the name already exists, it is re-defined in place so that the watchers
+// are notified. Vars of the root env are not re-defined by a restricted
+// view but shadowed.
+func (env *Env) define(name string, value Any, meta Map) *Var {
+	if om, isView := env.globals.(*overlayMap); isView && !om.owns(name) {
+		v := NewVar(name, value, meta)
+		env.globals.Store(name, v)
+		return v
+	}
+
+	if v, found := env.Var(name); found {
+		v.Define(value, meta)
+		return v
+	}
+
+	v := NewVar(name, value, meta)
+	env.globals.Store(name, v)
 	return v
 }
 
+// resolve returns the value of the global var bound to the name as seen by
+// env. Returns nil if no such var exists. Locals are resolved to slots during
+// analysis and hence are not considered.
+func (env *Env) resolve(sym string) Any {
+	v, found := env.Var(sym)
+	if !found {
+		return nil
+	}
+	return env.deref(v)
+}
+
+// deref returns the value of the var taking the thread-local bindings of
+// dynamic vars into account.
+func (env *Env) deref(v *Var) Any {
+	if v.Dynamic() {
+		sym := v.Name()
+		// thread-local bindings of dynamic vars shadow the root value.
+		for frame := env.bindings; frame != nil; frame = frame.prev {
+			if val, found := frame.vals[sym]; found {
+				return val
+			}
+		}
+	}
+
+	return v.Deref()
+}
+
+// carryMeta returns the expanded form with the metadata of the original form
+// merged into its own metadata. Entries of the expanded form take precedence.
+func carryMeta(orig, expanded Any) (Any, error) {
+	an, ok := orig.(Annotated)
+	if !ok || an.Meta() == nil {
+		return expanded, nil
+	}
+
+	target, ok := expanded.(Annotatable)
+	if !ok {
+		return expanded, nil
+	}
+
+	meta, err := mergeMeta(an.Meta(), target.Meta())
+	if err != nil {
+		return nil, err
+	}
+	return target.WithMeta(meta)
+}
+
+// withPos records the position of the form being analyzed for use by the
+// special form parsers and returns a function that restores the previous.
+func (env *Env) withPos(form Any) (restore func()) {
+	prev, prevOwn := env.pos, env.ownPos
+	env.ownPos = Position{}
+	if p, ok := form.(Positional); ok {
+		env.pos = p.Pos()
+		env.ownPos = env.pos
+	}
+	return func() { env.pos, env.ownPos = prev, prevOwn }
+}
+
+// IsDynamic returns true if the name follows the earmuff convention (e.g.,
+// `*out*`) used to mark dynamic vars. Dynamic vars can be re-bound for a
+// call tree using the binding form.
+func IsDynamic(name string) bool {
+	return len(name) > 2 && name[0] == '*' && name[len(name)-1] == '*'
+}
+
+// bindingFrame holds the thread-local values of dynamic vars established by
+// one binding form. Frames are never modified once created and hence can be
+// shared safely between an Env and its forks.
+type bindingFrame struct {
+	vals map[string]Any
+	prev *bindingFrame
+}
+
 type stackFrame struct {
-	Name string
-	Args []Any
-	Vars map[string]Any
+	Name   string
+	Pos    Position
+	Args   []Any
+	Locals []Any
+	names  []string // names of the local slots.
+}
+
+// isPending returns true if the name is being defined by a def form under
+// analysis. References to such names are resolved when evaluated to allow
+// recursive definitions.
+func (env *Env) isPending(name string) bool {
+	for _, p := range env.pending {
+		if p == name {
+			return true
+		}
+	}
+	return false
 }
 
 func newMutexMap() ConcurrentMap { return &mutexMap{} }
@@ -136,6 +446,21 @@ func (m *mutexMap) Store(name string, v Any) {
 	m.vs[name] = v
 }
 
+func (m *mutexMap) Delete(name string) {
+	m.Lock()
+	defer m.Unlock()
+	delete(m.vs, name)
+}
+
+func (m *mutexMap) Range(fn func(key string, val Any) bool) {
+	// iterate over a snapshot to allow fn to modify the map.
+	for k, v := range m.Map() {
+		if !fn(k, v) {
+			break
+		}
+	}
+}
+
 func (m *mutexMap) Map() map[string]Any {
 	m.RLock()
 	defer m.RUnlock()
@@ -147,3 +472,65 @@ func (m *mutexMap) Map() map[string]Any {
 
 	return native
 }
+
+// NewCopyOnWriteMap returns a ConcurrentMap meant for read-mostly workloads
+// such as global bindings that are defined once and resolved many times from
+// many forked envs. Load and Range never block. Every Store and Delete copies
+// the entire map and hence writes are O(n). Can be used as the factory with
+// WithGlobals().
+func NewCopyOnWriteMap() ConcurrentMap {
+	m := &cowMap{}
+	m.vs.Store(map[string]Any{})
+	return m
+}
+
+// cowMap implements ConcurrentMap by atomically replacing an immutable native
+// map on every write.
+type cowMap struct {
+	mu sync.Mutex // serializes writers.
+	vs atomic.Value
+}
+
+func (m *cowMap) Load(name string) (v Any, ok bool) {
+	v, ok = m.load()[name]
+	return
+}
+
+func (m *cowMap) Store(name string, v Any) {
+	m.update(func(vs map[string]Any) { vs[name] = v })
+}
+
+func (m *cowMap) Delete(name string) {
+	if _, found := m.Load(name); !found {
+		return
+	}
+	m.update(func(vs map[string]Any) { delete(vs, name) })
+}
+
+func (m *cowMap) Range(fn func(key string, val Any) bool) {
+	for k, v := range m.load() {
+		if !fn(k, v) {
+			break
+		}
+	}
+}
+
+func (m *cowMap) Map() map[string]Any {
+	cur := m.load()
+	native := make(map[string]Any, len(cur))
+	for k, v := range cur {
+		native[k] = v
+	}
+	return native
+}
+
+func (m *cowMap) update(fn func(vs map[string]Any)) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	next := m.Map()
+	fn(next)
+	m.vs.Store(next)
+}
+
+func (m *cowMap) load() map[string]Any { return m.vs.Load().(map[string]Any) }
@@ -0,0 +1,334 @@
+package parens_test
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/spy16/parens"
+)
+
+func TestEnv_Bind(t *testing.T) {
+	t.Parallel()
+
+	root := parens.New(parens.WithGlobals(map[string]parens.Any{
+		"*user*": parens.String("anonymous"),
+		"limit":  parens.Int64(10),
+	}, nil))
+
+	t.Run("ForkIsolation", func(t *testing.T) {
+		alice, bob := root.Fork(), root.Fork()
+
+		restoreA, err := alice.Bind(map[string]parens.Any{"*user*": parens.String("alice")})
+		requireNoErr(t, err)
+		defer restoreA()
+
+		restoreB, err := bob.Bind(map[string]parens.Any{"*user*": parens.String("bob")})
+		requireNoErr(t, err)
+		defer restoreB()
+
+		assertEval(t, alice, parens.Symbol("*user*"), parens.String("alice"))
+		assertEval(t, bob, parens.Symbol("*user*"), parens.String("bob"))
+		assertEval(t, root, parens.Symbol("*user*"), parens.String("anonymous"))
+
+		// fork inherits bindings active at the time of fork.
+		assertEval(t, alice.Fork(), parens.Symbol("*user*"), parens.String("alice"))
+	})
+
+	t.Run("Restore", func(t *testing.T) {
+		env := root.Fork()
+		restore, err := env.Bind(map[string]parens.Any{"*user*": parens.String("alice")})
+		requireNoErr(t, err)
+		restore()
+
+		assertEval(t, env, parens.Symbol("*user*"), parens.String("anonymous"))
+	})
+
+	t.Run("NotDynamic", func(t *testing.T) {
+		_, err := root.Fork().Bind(map[string]parens.Any{"limit": parens.Int64(1)})
+		if !errors.Is(err, parens.ErrNotDynamic) {
+			t.Errorf("expecting ErrNotDynamic, got %v", err)
+		}
+	})
+}
+
+func assertEval(t *testing.T, env *parens.Env, form parens.Any, want parens.Any) {
+	got, err := env.Eval(form)
+	requireNoErr(t, err)
+	assertEqual(t, want, got)
+}
+
+func TestEnv_Globals(t *testing.T) {
+	t.Parallel()
+
+	env := parens.New(parens.WithGlobals(map[string]parens.Any{
+		"rules.a": parens.Int64(1),
+		"rules.b": parens.Int64(2),
+		"other":   parens.Int64(3),
+	}, nil))
+
+	var names []string
+	for _, v := range env.Globals("rules.") {
+		names = append(names, v.Name())
+	}
+	assertEqual(t, []string{"rules.a", "rules.b"}, names)
+
+	if all := env.Globals(""); len(all) < 3 {
+		t.Errorf("expecting at least 3 globals, got %d", len(all))
+	}
+}
+
+func TestEnv_Undef(t *testing.T) {
+	t.Parallel()
+
+	env := parens.New(parens.WithGlobals(map[string]parens.Any{
+		"rule-a": parens.Int64(1),
+		"rule-b": parens.Int64(2),
+		"rule-c": parens.Int64(3),
+	}, nil), parens.WithCore())
+
+	assertEqual(t, true, env.Undef("rule-a"))
+	assertEqual(t, false, env.Undef("rule-a"))
+
+	assertEval(t, env, readOne(t, `(undef rule-b)`), parens.Bool(true))
+	assertEval(t, env, readOne(t, `(ns-unmap 'rule-c)`), parens.Nil{})
+
+	for _, name := range []string{"rule-a", "rule-b", "rule-c"} {
+		if _, err := env.Eval(parens.Symbol(name)); !errors.Is(err, parens.ErrNotFound) {
+			t.Errorf("expecting ErrNotFound for '%s', got %v", name, err)
+		}
+	}
+}
+
+func TestCopyOnWriteMap(t *testing.T) {
+	t.Parallel()
+
+	m := parens.NewCopyOnWriteMap()
+	m.Store("a", parens.Int64(1))
+	m.Store("b", parens.Int64(2))
+
+	snapshot := m.Map()
+	m.Delete("a")
+	m.Delete("unknown")
+
+	if _, found := m.Load("a"); found {
+		t.Errorf("expecting 'a' to be deleted")
+	}
+	assertEqual(t, 2, len(snapshot))
+
+	var keys []string
+	m.Range(func(key string, _ parens.Any) bool {
+		keys = append(keys, key)
+		return true
+	})
+	assertEqual(t, []string{"b"}, keys)
+
+	env := parens.New(parens.WithGlobals(map[string]parens.Any{
+		"limit": parens.Int64(10),
+	}, parens.NewCopyOnWriteMap))
+	assertEval(t, env, parens.Symbol("limit"), parens.Int64(10))
+}
+
+func TestWithGlobals_Factory(t *testing.T) {
+	t.Parallel()
+
+	m := &loadCountingMap{ConcurrentMap: parens.NewCopyOnWriteMap()}
+	env := parens.New(
+		parens.WithGlobals(map[string]parens.Any{"limit": parens.Int64(10)}, nil),
+		parens.WithCore(),
+		parens.WithGlobals(nil, func() parens.ConcurrentMap { return m }),
+	)
+
+	assertEval(t, env, parens.Symbol("limit"), parens.Int64(10))
+	if atomic.LoadInt32(&m.loads) == 0 {
+		t.Errorf("expecting globals to be loaded from the map created by factory")
+	}
+
+	for _, name := range []string{"limit", "hash-map"} {
+		if _, found := m.Load(name); !found {
+			t.Errorf("expecting '%s' to be moved to the map created by factory", name)
+		}
+	}
+}
+
+func BenchmarkGlobals_ParallelResolve(b *testing.B) {
+	globals := map[string]parens.Any{}
+	for i := 0; i < 256; i++ {
+		globals[fmt.Sprintf("rule-%d", i)] = parens.Int64(i)
+	}
+
+	factories := map[string]func() parens.ConcurrentMap{
+		"MutexMap":       nil,
+		"CopyOnWriteMap": parens.NewCopyOnWriteMap,
+	}
+
+	for name, factory := range factories {
+		b.Run(name, func(b *testing.B) {
+			env := parens.New(parens.WithGlobals(globals, factory))
+			sym := parens.Symbol("rule-42")
+
+			b.ReportAllocs()
+			b.ResetTimer()
+			b.RunParallel(func(pb *testing.PB) {
+				child := env.Fork()
+				for pb.Next() {
+					if _, err := child.Eval(sym); err != nil {
+						b.Fatalf("unexpected error: %v", err)
+					}
+				}
+			})
+		})
+	}
+}
+
+func TestEnv_Compile(t *testing.T) {
+	t.Parallel()
+
+	var calls int32
+	env := parens.New(parens.WithGlobals(map[string]parens.Any{
+		"*user*": parens.String("anonymous"),
+		"greet": invokableFunc(func(_ *parens.Env, args ...parens.Any) (parens.Any, error) {
+			atomic.AddInt32(&calls, 1)
+			return parens.NewList(parens.Keyword("hello"), args[0]), nil
+		}),
+	}, nil))
+
+	t.Run("NoSideEffects", func(t *testing.T) {
+		_, err := env.Fork().Compile(readOne(t, `(def greeting (greet *user*))`))
+		requireNoErr(t, err)
+		assertEqual(t, int32(0), atomic.LoadInt32(&calls))
+
+		if _, found := env.Var("greeting"); found {
+			t.Errorf("compile must not define globals")
+		}
+	})
+
+	t.Run("ConcurrentRuns", func(t *testing.T) {
+		prog, err := env.Compile(readOne(t, `(greet *user*)`))
+		requireNoErr(t, err)
+
+		var wg sync.WaitGroup
+		for i := 0; i < 10; i++ {
+			wg.Add(1)
+			go func(i int) {
+				defer wg.Done()
+
+				user := parens.String(fmt.Sprintf("user-%d", i))
+				child := env.Fork()
+				restore, err := child.Bind(map[string]parens.Any{"*user*": user})
+				if err != nil {
+					t.Errorf("unexpected error: %v", err)
+					return
+				}
+				defer restore()
+
+				got, err := prog.Run(child)
+				if err != nil {
+					t.Errorf("unexpected error: %v", err)
+					return
+				}
+				assertEqual(t, parens.NewList(parens.Keyword("hello"), user), got)
+			}(i)
+		}
+		wg.Wait()
+	})
+
+	t.Run("AnalysisError", func(t *testing.T) {
+		_, err := env.Compile(readOne(t, `(greet unknown)`))
+		if !errors.Is(err, parens.ErrNotFound) {
+			t.Errorf("expecting ErrNotFound, got %v", err)
+		}
+	})
+}
+
+func TestEnv_Meta(t *testing.T) {
+	t.Parallel()
+
+	forEachBackend(t, func(t *testing.T, opts ...parens.Option) {
+		t.Run("DefName", func(t *testing.T) {
+			env := parens.New(append(opts, parens.WithCore())...)
+			_, err := env.Eval(readOne(t, `(def ^{:private true :doc "secret"} x "doc" 1)`))
+			requireNoErr(t, err)
+
+			v, found := env.Var("x")
+			assertEqual(t, true, found)
+			assertEqual(t, parens.Bool(true), entry(v.Meta(), parens.Keyword("private")))
+			assertEqual(t, parens.String("secret"), entry(v.Meta(), parens.Keyword("doc")))
+		})
+
+		t.Run("Expansion", func(t *testing.T) {
+			var analyzed []parens.Any
+			env := parens.New(append(opts, parens.WithCore(),
+				parens.WithExpander(listExpander{}),
+				parens.WithTracer(analyzeTracer(func(form parens.Any) { analyzed = append(analyzed, form) })),
+			)...)
+
+			_, err := env.Eval(readOne(t, `^:checked (twice 1)`))
+			requireNoErr(t, err)
+
+			outer := analyzed[len(analyzed)-1]
+			s, _ := outer.SExpr()
+			assertEqual(t, "(hash-map 1 1)", s)
+			assertEqual(t, parens.Bool(true), entry(outer.(parens.Annotated).Meta(), parens.Keyword("checked")))
+		})
+	})
+}
+
+func entry(m parens.Map, key parens.Any) parens.Any {
+	if m == nil {
+		return nil
+	}
+	v, _ := m.EntryAt(key)
+	return v
+}
+
+// listExpander expands `(twice x)` to `(hash-map x x)`.
+type loadCountingMap struct {
+	parens.ConcurrentMap
+	loads int32
+}
+
+func (m *loadCountingMap) Load(key string) (parens.Any, bool) {
+	atomic.AddInt32(&m.loads, 1)
+	return m.ConcurrentMap.Load(key)
+}
+
+type listExpander struct{}
+
+func (listExpander) Expand(_ *parens.Env, form parens.Any) (parens.Any, error) {
+	seq, ok := form.(parens.Seq)
+	if !ok {
+		return nil, nil
+	}
+
+	first, err := seq.First()
+	if err != nil || first == nil || !parens.Symbol("twice").Equals(first) {
+		return nil, err
+	}
+
+	next, err := seq.Next()
+	if err != nil {
+		return nil, err
+	}
+	arg, err := next.First()
+	if err != nil {
+		return nil, err
+	}
+	return parens.NewList(parens.Symbol("hash-map"), arg, arg), nil
+}
+
+type analyzeTracer func(form parens.Any)
+
+func (at analyzeTracer) Expanded(_ *parens.Env, _, _ parens.Any) {}
+
+func (at analyzeTracer) Analyzed(_ *parens.Env, form parens.Any, _ parens.Expr, _ error) {
+	at(form)
+}
+
+func (at analyzeTracer) InvokeStart(_ *parens.Env, _ string, _ []parens.Any) {}
+
+func (at analyzeTracer) InvokeEnd(_ *parens.Env, _ string, _ []parens.Any, _ parens.Any, _ error, _ time.Duration) {
+}
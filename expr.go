@@ -2,7 +2,6 @@ package parens
 
 import (
 	"fmt"
-	"reflect"
 	"strings"
 )
 
@@ -13,6 +12,12 @@ var (
 	_ Expr = (*InvokeExpr)(nil)
 	_ Expr = (*IfExpr)(nil)
 	_ Expr = (*DoExpr)(nil)
+	_ Expr = (*VarExpr)(nil)
+	_ Expr = (*BindingExpr)(nil)
+	_ Expr = (*LocalExpr)(nil)
+	_ Expr = (*FnExpr)(nil)
+	_ Expr = (*LetExpr)(nil)
+	_ Expr = (*UndefExpr)(nil)
 )
 
 // ConstExpr returns the Const value wrapped inside when evaluated. It has
@@ -34,20 +39,222 @@ func (qe QuoteExpr) Eval(_ *Env) (Any, error) {
 // DefExpr creates a global binding with the Name when evaluated.
 type DefExpr struct {
 	Name  string
-	Value Any
+	Value Expr
+	Meta  Map
 }
 
-// Eval creates a symbol binding in the global (root) stack frame.
+// Eval evaluates the value and creates a symbol binding in the global (root)
+// stack frame. If the value is a function, its parameter list is recorded
+// as `:arglists` in the var metadata.
 func (de DefExpr) Eval(env *Env) (Any, error) {
 	de.Name = strings.TrimSpace(de.Name)
 	if de.Name == "" {
 		return nil, fmt.Errorf("%w: '%s'", ErrInvalidBindName, de.Name)
 	}
 
-	env.setGlobal(de.Name, de.Value)
+	val, err := de.Value.Eval(env)
+	if err != nil {
+		return nil, err
+	}
+
+	return de.bind(env, val)
+}
+
+// bind defines the var for the value. de.Name must be trimmed.
+func (de DefExpr) bind(env *Env, val Any) (Any, error) {
+	if err := env.checkGlobal(de.Name); err != nil {
+		return nil, err
+	}
+
+	meta := de.Meta
+	if fn, ok := val.(*Fn); ok {
+		var err error
+		if meta, err = assoc(meta, Keyword("arglists"), NewList(fn.Arglist())); err != nil {
+			return nil, err
+		}
+	}
+
+	env.define(de.Name, val, meta)
 	return Symbol(de.Name), nil
 }
 
+// VarExpr resolves the value of a global var when evaluated. If Var is set,
+// the var cell resolved during analysis is used directly. Otherwise the var
+// is looked up by Name on every evaluation.
+type VarExpr struct {
+	Name string
+	Var  *Var
+}
+
+// Eval returns the value of the var as seen by the env.
+func (ve VarExpr) Eval(env *Env) (Any, error) {
+	var v Any
+	if ve.Var != nil {
+		// expr may have been analyzed by an env with fewer restrictions.
+		if err := env.checkGlobal(ve.Name); err != nil {
+			return nil, err
+		}
+		v = env.deref(ve.Var)
+	} else if v = env.resolve(ve.Name); v == nil {
+		return nil, Error{
+			Cause:   ErrNotFound,
+			Message: ve.Name,
+		}
+	}
+
+	if err := env.checkInterop(ve.Name, v); err != nil {
+		return nil, err
+	}
+	return v, nil
+}
+
+// LocalExpr resolves a local binding (e.g., function parameter) from the
+// slot of the top stack frame when evaluated.
+type LocalExpr struct {
+	Name string
+	Slot int
+}
+
+// Eval returns the value in the slot of the current stack frame.
+func (le LocalExpr) Eval(env *Env) (Any, error) {
+	if len(env.stack) > 0 {
+		if locals := env.stack[len(env.stack)-1].Locals; le.Slot < len(locals) {
+			return locals[le.Slot], nil
+		}
+	}
+
+	return nil, Error{
+		Cause:   ErrNotFound,
+		Message: le.Name,
+	}
+}
+
+// FnExpr represents the (fn name? (param*) expr*) form.
+type FnExpr struct {
+	Name     string
+	Params   []string
+	Variadic bool
+	Body     Expr
+
+	// Locals is the names of the slots in the function frame. Parameters
+	// take the first slots followed by the name of the function (if any).
+	Locals []string
+
+	// Captures is the list of locals of the enclosing frame copied into the
+	// function frame.
+	Captures []Capture
+}
+
+// Eval creates a function value. Locals of the enclosing frame referenced
+// by the function are captured.
+func (fe FnExpr) Eval(env *Env) (Any, error) {
+	fn := &Fn{
+		Name:     fe.Name,
+		Params:   fe.Params,
+		Variadic: fe.Variadic,
+		Body:     fe.Body,
+		locals:   fe.Locals,
+		captures: fe.Captures,
+	}
+
+	// a function is one value however many locals it captures.
+	if err := env.Alloc(0); err != nil {
+		return nil, err
+	}
+
+	if len(fe.Captures) > 0 {
+		if len(env.stack) == 0 {
+			return nil, Error{
+				Cause:   ErrNotFound,
+				Message: "no stack frame to capture locals from",
+			}
+		}
+
+		locals := env.stack[len(env.stack)-1].Locals
+		fn.closure = make([]Any, len(fe.Captures))
+		for i, c := range fe.Captures {
+			fn.closure[i] = locals[c.From]
+		}
+	}
+
+	return fn, nil
+}
+
+// LetExpr represents the (let (name value*) expr*) form. Values are stored
+// in the given Slots of the current frame. If Frame is not nil, a new stack
+// frame with the named slots is created for the evaluation.
+type LetExpr struct {
+	Slots  []int
+	Values []Expr
+	Body   Expr
+	Frame  []string
+}
+
+// Eval binds the values to the slots in order and evaluates the body.
+func (le LetExpr) Eval(env *Env) (Any, error) {
+	if le.Frame != nil {
+		env.push(stackFrame{
+			Name:   "let",
+			Locals: make([]Any, len(le.Frame)),
+			names:  le.Frame,
+		})
+		defer env.pop()
+	}
+
+	if len(env.stack) == 0 {
+		return nil, Error{
+			Cause:   ErrNotFound,
+			Message: "no stack frame for let bindings",
+		}
+	}
+
+	for i, val := range le.Values {
+		v, err := val.Eval(env)
+		if err != nil {
+			return nil, err
+		}
+		env.stack[len(env.stack)-1].Locals[le.Slots[i]] = v
+	}
+
+	return le.Body.Eval(env)
+}
+
+// BindingExpr represents the (binding (name value*) expr*) form.
+type BindingExpr struct {
+	Names  []string
+	Values []Expr
+	Body   Expr
+}
+
+// Eval re-binds the dynamic vars for the duration of the body evaluation.
+// Previous bindings are restored even if the body fails.
+func (be BindingExpr) Eval(env *Env) (Any, error) {
+	vals := make(map[string]Any, len(be.Names))
+	for i, name := range be.Names {
+		v, err := be.Values[i].Eval(env)
+		if err != nil {
+			return nil, err
+		}
+		vals[name] = v
+	}
+
+	restore, err := env.Bind(vals)
+	if err != nil {
+		return nil, err
+	}
+	defer restore()
+
+	return be.Body.Eval(env)
+}
+
+// UndefExpr removes a global binding when evaluated.
+type UndefExpr struct{ Name string }
+
+// Eval removes the global binding and returns true if the binding existed.
+func (ue UndefExpr) Eval(env *Env) (Any, error) {
+	return Bool(env.Undef(ue.Name)), nil
+}
+
 // IfExpr represents the if-then-else form.
 type IfExpr struct{ Test, Then, Else Expr }
 
@@ -79,6 +286,10 @@ func (de DoExpr) Eval(env *Env) (Any, error) {
 	var err error
 
 	for _, expr := range de.Exprs {
+		if err := env.step(); err != nil {
+			return nil, err
+		}
+
 		res, err = expr.Eval(env)
 		if err != nil {
 			return nil, err
@@ -94,6 +305,7 @@ func (de DoExpr) Eval(env *Env) (Any, error) {
 // InvokeExpr performs invocation of target when evaluated.
 type InvokeExpr struct {
 	Name   string
+	Pos    Position
 	Target Expr
 	Args   []Expr
 }
@@ -105,14 +317,6 @@ func (ie InvokeExpr) Eval(env *Env) (Any, error) {
 		return nil, err
 	}
 
-	fn, ok := val.(Invokable)
-	if !ok {
-		return nil, Error{
-			Cause:   ErrNotInvokable,
-			Message: fmt.Sprintf("value of type '%s' is not invokable", reflect.TypeOf(val)),
-		}
-	}
-
 	var args []Any
 	for _, ae := range ie.Args {
 		v, err := ae.Eval(env)
@@ -122,27 +326,33 @@ func (ie InvokeExpr) Eval(env *Env) (Any, error) {
 		args = append(args, v)
 	}
 
-	env.push(stackFrame{
-		Name: ie.Name,
-		Args: args,
-		Vars: map[string]Any{},
-	})
-	defer env.pop()
-
-	return fn.Invoke(env, args...)
+	return env.invoke(ie.Name, ie.Pos, val, args)
 }
 
 // GoExpr evaluates an expression in a separate goroutine.
 type GoExpr struct {
-	Value Any
+	Value Expr
 }
 
 // Eval forks the given context to get a child context and launches goroutine
-// with the child context to evaluate the
+// with the child context to evaluate the expression. Locals of the current
+// stack frame remain accessible in the child. In deterministic mode, the
+// expression is evaluated in the child before returning instead.
 func (ge GoExpr) Eval(env *Env) (Any, error) {
-	child := env.fork()
+	child := env.Fork()
+	if len(env.stack) > 0 {
+		top := env.stack[len(env.stack)-1]
+		top.Locals = append([]Any(nil), top.Locals...)
+		child.push(top)
+	}
+
+	if env.det != nil {
+		_, _ = ge.Value.Eval(child)
+		return nil, nil
+	}
+
 	go func() {
-		_, _ = child.Eval(ge.Value)
+		_, _ = ge.Value.Eval(child)
 	}()
 	return nil, nil
 }
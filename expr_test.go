@@ -1,6 +1,7 @@
 package parens_test
 
 import (
+	"errors"
 	"reflect"
 	"strings"
 	"testing"
@@ -15,7 +16,7 @@ func TestDoExpr_Eval(t *testing.T) {
 
 	t.Run("No Body", func(t *testing.T) {
 		de := parens.DoExpr{}
-		res, err := de.Eval(parens.New())
+		res, err := evalExpr(t, de, parens.New())
 		requireNoErr(t, err)
 		assertEqual(t, parens.Nil{}, res)
 	})
@@ -26,7 +27,7 @@ func TestDoExpr_Eval(t *testing.T) {
 				&parens.ConstExpr{Const: parens.Symbol("foo")},
 			},
 		}
-		res, err := de.Eval(parens.New())
+		res, err := evalExpr(t, de, parens.New())
 		requireNoErr(t, err)
 		assertEqual(t, parens.Symbol("foo"), res)
 	})
@@ -41,7 +42,7 @@ func TestIfExpr_Eval(t *testing.T) {
 			Then: &parens.ConstExpr{Const: parens.String("then")},
 			Else: &parens.ConstExpr{Const: parens.String("else")},
 		}
-		res, err := ie.Eval(parens.New())
+		res, err := evalExpr(t, ie, parens.New())
 		requireNoErr(t, err)
 		assertEqual(t, parens.String("else"), res)
 	})
@@ -52,7 +53,7 @@ func TestIfExpr_Eval(t *testing.T) {
 			Then: &parens.ConstExpr{Const: parens.String("then")},
 			Else: &parens.ConstExpr{Const: parens.String("else")},
 		}
-		res, err := ie.Eval(parens.New())
+		res, err := evalExpr(t, ie, parens.New())
 		requireNoErr(t, err)
 		assertEqual(t, parens.String("then"), res)
 	})
@@ -63,7 +64,7 @@ func TestIfExpr_Eval(t *testing.T) {
 			Then: &parens.ConstExpr{Const: parens.String("then")},
 			Else: &parens.ConstExpr{Const: parens.String("else")},
 		}
-		res, err := ie.Eval(parens.New())
+		res, err := evalExpr(t, ie, parens.New())
 		requireNoErr(t, err)
 		assertEqual(t, parens.String("then"), res)
 	})
@@ -72,30 +73,289 @@ func TestIfExpr_Eval(t *testing.T) {
 		ie := parens.IfExpr{
 			Test: &parens.ConstExpr{Const: parens.String("foo")},
 		}
-		res, err := ie.Eval(parens.New())
+		res, err := evalExpr(t, ie, parens.New())
 		requireNoErr(t, err)
 		assertEqual(t, parens.Nil{}, res)
 	})
+
+	t.Run("Special Form", func(t *testing.T) {
+		res, err := evalBackends(t, `(if (get (hash-map :a 1) :a) :then :else)`, parens.WithCore())
+		requireNoErr(t, err)
+		assertEqual(t, parens.Keyword("then"), res)
+
+		res, err = evalBackends(t, `(if (get (hash-map) :a) :then)`, parens.WithCore())
+		requireNoErr(t, err)
+		assertEqual(t, parens.Nil{}, res)
+
+		_, err = evalBackends(t, `(if true)`, parens.WithCore())
+		assertErr(t, err)
+	})
 }
 
 func TestDefExpr_Eval(t *testing.T) {
 	t.Parallel()
 
 	t.Run("Invalid Name", func(t *testing.T) {
-		de := parens.DefExpr{Name: "", Value: parens.Int64(10)}
+		de := parens.DefExpr{Name: "", Value: &parens.ConstExpr{Const: parens.Int64(10)}}
 		v, err := de.Eval(nil)
 		assertErr(t, err)
 		assertEqual(t, nil, v)
+
+		v, err = evalExpr(t, de, parens.New())
+		assertErr(t, err)
+		assertEqual(t, nil, v)
 	})
 
 	t.Run("Success", func(t *testing.T) {
-		de := parens.DefExpr{Name: "foo", Value: parens.Int64(10)}
-		v, err := de.Eval(parens.New())
+		de := parens.DefExpr{Name: "foo", Value: &parens.ConstExpr{Const: parens.Int64(10)}}
+		v, err := evalExpr(t, de, parens.New())
 		requireNoErr(t, err)
 		assertEqual(t, parens.Symbol("foo"), v)
 	})
 }
 
+func TestBindingExpr_Eval(t *testing.T) {
+	t.Parallel()
+
+	globals := func() parens.Option {
+		return parens.WithGlobals(map[string]parens.Any{
+			"*out*": parens.String("stdout"),
+			"fail": invokableFunc(func(_ *parens.Env, _ ...parens.Any) (parens.Any, error) {
+				return nil, errors.New("failed")
+			}),
+		}, nil)
+	}
+
+	t.Run("Rebinds", func(t *testing.T) {
+		res, err := evalBackends(t, `(binding (*out* "buffer") *out*)`, globals())
+		requireNoErr(t, err)
+		assertEqual(t, parens.String("buffer"), res)
+
+		res, err = evalBackends(t, `(binding (*out* "buffer") *out*) *out*`, globals())
+		requireNoErr(t, err)
+		assertEqual(t, parens.String("stdout"), res)
+	})
+
+	t.Run("RestoredOnError", func(t *testing.T) {
+		forEachBackend(t, func(t *testing.T, opts ...parens.Option) {
+			env := parens.New(append(opts, globals())...)
+			_, err := env.Eval(readOne(t, `(binding (*out* "buffer") (fail))`))
+			assertErr(t, err)
+
+			res, err := env.Eval(parens.Symbol("*out*"))
+			requireNoErr(t, err)
+			assertEqual(t, parens.String("stdout"), res)
+		})
+	})
+
+	t.Run("NotDynamic", func(t *testing.T) {
+		_, err := evalBackends(t, `(binding (fail 1) 10)`, globals())
+		if !errors.Is(err, parens.ErrNotDynamic) {
+			t.Errorf("expecting ErrNotDynamic, got %v", err)
+		}
+	})
+
+	t.Run("NotDynamicAnalyze", func(t *testing.T) {
+		forEachBackend(t, func(t *testing.T, opts ...parens.Option) {
+			_, err := parens.New(append(opts, globals())...).Analyze(readOne(t, `(binding (undefined 1) 10)`))
+			if !errors.Is(err, parens.ErrNotDynamic) {
+				t.Errorf("expecting ErrNotDynamic, got %v", err)
+			}
+		})
+	})
+
+	t.Run("DynamicMeta", func(t *testing.T) {
+		res, err := evalBackends(t, `(def ^:dynamic depth 0) (binding (depth 1) depth)`, globals())
+		requireNoErr(t, err)
+		assertEqual(t, parens.Int64(1), res)
+	})
+
+	t.Run("Undefined", func(t *testing.T) {
+		_, err := evalBackends(t, `(binding (*err* 1) 10)`, globals())
+		if !errors.Is(err, parens.ErrNotFound) {
+			t.Errorf("expecting ErrNotFound, got %v", err)
+		}
+	})
+
+	t.Run("OddBindings", func(t *testing.T) {
+		_, err := evalBackends(t, `(binding (*out*) 10)`, globals())
+		assertErr(t, err)
+	})
+
+}
+
+func TestFnExpr_Eval(t *testing.T) {
+	t.Parallel()
+
+	table := []struct {
+		title   string
+		src     string
+		want    parens.Any
+		wantErr bool
+	}{
+		{
+			title: "Identity",
+			src:   `((fn (x) x) 10)`,
+			want:  parens.Int64(10),
+		},
+		{
+			title: "Variadic",
+			src:   `((fn (x & more) more) 1 2 3)`,
+			want:  parens.NewList(parens.Int64(2), parens.Int64(3)),
+		},
+		{
+			title: "Closure",
+			src:   `(((fn (x) (fn (y) x)) :outer) :inner)`,
+			want:  parens.Keyword("outer"),
+		},
+		{
+			title: "SelfReference",
+			src:   `((fn self (x) self) 1)`,
+			want:  nil,
+		},
+		{
+			title:   "WrongArity",
+			src:     `((fn (x) x))`,
+			wantErr: true,
+		},
+		{
+			title:   "InvalidVariadic",
+			src:     `(fn (x & a b) x)`,
+			wantErr: true,
+		},
+		{
+			title:   "UnknownSymbol",
+			src:     `(fn (x) y)`,
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range table {
+		t.Run(tt.title, func(t *testing.T) {
+			got, err := evalBackends(t, tt.src, parens.WithCore())
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Eval() error = %#v, wantErr %#v", err, tt.wantErr)
+			} else if tt.wantErr {
+				return
+			}
+
+			if tt.want == nil {
+				if _, isFn := got.(*parens.Fn); !isFn {
+					t.Errorf("expecting *parens.Fn, got %#v", got)
+				}
+				return
+			}
+			assertEqual(t, tt.want, got)
+		})
+	}
+
+}
+
+func TestLetExpr_Eval(t *testing.T) {
+	t.Parallel()
+
+	table := []struct {
+		title   string
+		src     string
+		want    parens.Any
+		wantErr bool
+	}{
+		{
+			title: "TopLevel",
+			src:   `(let (x 1 y :two) y)`,
+			want:  parens.Keyword("two"),
+		},
+		{
+			title: "SeesEarlierBindings",
+			src:   `(let (x 1 y x) y)`,
+			want:  parens.Int64(1),
+		},
+		{
+			title: "Shadowing",
+			src:   `(let (x 1) (let (x 2) x))`,
+			want:  parens.Int64(2),
+		},
+		{
+			title: "InsideFn",
+			src:   `((fn (x) (let (y x) y)) :arg)`,
+			want:  parens.Keyword("arg"),
+		},
+		{
+			title: "NestedCapture",
+			src:   `(((fn (x) (let (y x) (fn () (let (z y) (fn () z))))) :deep))`,
+			want:  nil,
+		},
+		{
+			title: "MultiLevelCapture",
+			src:   `((((fn (a) (fn (b) (fn (c) a))) :a) :b) :c)`,
+			want:  parens.Keyword("a"),
+		},
+		{
+			title:   "NotVisibleAfter",
+			src:     `((fn () (let (x 1) x) x))`,
+			wantErr: true,
+		},
+		{
+			title:   "OddBindings",
+			src:     `(let (x) x)`,
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range table {
+		t.Run(tt.title, func(t *testing.T) {
+			got, err := evalBackends(t, tt.src, parens.WithCore())
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Eval() error = %#v, wantErr %#v", err, tt.wantErr)
+			} else if tt.wantErr {
+				return
+			}
+
+			if tt.want == nil {
+				if _, isFn := got.(*parens.Fn); !isFn {
+					t.Errorf("expecting *parens.Fn, got %#v", got)
+				}
+				return
+			}
+			assertEqual(t, tt.want, got)
+		})
+	}
+
+}
+
+func TestVarExpr_Redefinition(t *testing.T) {
+	t.Parallel()
+
+	env := parens.New(parens.WithCore())
+
+	_, err := env.Eval(readOne(t, `(def greeting :hello)`))
+	requireNoErr(t, err)
+
+	prog, err := env.Compile(readOne(t, `(fn () greeting)`))
+	requireNoErr(t, err)
+	greet, err := prog.Run(env)
+	requireNoErr(t, err)
+
+	_, err = env.Eval(readOne(t, `(def greeting :bye)`))
+	requireNoErr(t, err)
+
+	res, err := greet.(*parens.Fn).Invoke(env)
+	requireNoErr(t, err)
+	assertEqual(t, parens.Keyword("bye"), res)
+
+	t.Run("SelfReference", func(t *testing.T) {
+		_, err := env.Eval(readOne(t, `(def self (fn () self))`))
+		requireNoErr(t, err)
+
+		res, err := env.Eval(readOne(t, `(((self)))`))
+		requireNoErr(t, err)
+		if _, isFn := res.(*parens.Fn); !isFn {
+			t.Errorf("expecting *parens.Fn, got %#v", res)
+		}
+	})
+
+}
+
 func TestQuoteExpr_Eval(t *testing.T) {
 	want := parens.NewList()
 
@@ -111,7 +371,7 @@ func TestGoExpr_Eval(t *testing.T) {
 	actual, err := r.One()
 	requireNoErr(t, err)
 
-	env := parens.New()
+	env := parens.New(parens.WithCore())
 	_, _ = env.Eval(actual)
 	time.Sleep(5 * time.Millisecond)
 
@@ -125,9 +385,83 @@ func TestGoExpr_Eval(t *testing.T) {
 		t.Errorf("expected keyword value of \"keyword\", got \"%s\"", string(kw))
 		return
 	}
+
+}
+
+func TestExpr_Backends(t *testing.T) {
+	t.Parallel()
+
+	table := []struct {
+		title   string
+		src     string
+		want    string
+		wantErr bool
+	}{
+		{title: "Body", src: `((fn () 1 :two "three"))`, want: `"three"`},
+		{title: "If", src: `(if nil :then :else)`, want: ":else"},
+		{title: "Quote", src: `'(a b)`, want: "(a b)"},
+		{title: "Def", src: `(def x 1)`, want: "x"},
+		{title: "Let", src: `(let (x 1 y x) (let (x 2) (list x y)))`, want: "(2 1)"},
+		{title: "Fn", src: `(((fn (a) (fn (b) (list a b))) 1) 2)`, want: "(1 2)"},
+		{title: "FnArity", src: `((fn (a) a))`, wantErr: true},
+		{title: "Binding", src: `(binding (*out* :err) (list *out*))`, want: "(:err)"},
+		{title: "BindingRestored", src: `((fn () (binding (*out* :err) *out*) *out*))`, want: `"stdout"`},
+		{title: "NotInvokable", src: `(1 2)`, wantErr: true},
+		{title: "NotFound", src: `(list unknown)`, wantErr: true},
+		{
+			title: "NestedFrames",
+			src:   `((fn (a b) (list ((fn (x) (list x a)) b) a)) 1 2)`,
+			want:  "((2 1) 1)",
+		},
+		{
+			title: "RetainedArgs",
+			src:   `((fn () (keep 1 2) (keep 3 4) (keep 5 6) (kept)))`,
+			want:  "(5 6)",
+		},
+	}
+
+	for _, tt := range table {
+		t.Run(tt.title, func(t *testing.T) {
+			var kept []parens.Any
+			got, err := evalBackends(t, tt.src, parens.WithGlobals(map[string]parens.Any{
+				"*out*": parens.String("stdout"),
+				"list":  parens.GoFunc(listFn),
+				"keep": invokableFunc(func(_ *parens.Env, args ...parens.Any) (parens.Any, error) {
+					kept = args
+					return parens.Nil{}, nil
+				}),
+				"kept": invokableFunc(func(_ *parens.Env, _ ...parens.Any) (parens.Any, error) {
+					return parens.NewList(kept...), nil
+				}),
+			}, nil))
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Eval() error = %#v, wantErr %#v", err, tt.wantErr)
+			} else if tt.wantErr {
+				return
+			}
+
+			s, err := got.SExpr()
+			requireNoErr(t, err)
+			assertEqual(t, tt.want, s)
+		})
+	}
+}
+
+type invokableFunc func(env *parens.Env, args ...parens.Any) (parens.Any, error)
+
+func (fn invokableFunc) SExpr() (string, error) { return "<fn>", nil }
+
+func (fn invokableFunc) Invoke(env *parens.Env, args ...parens.Any) (parens.Any, error) {
+	return fn(env, args...)
+}
+
+func readOne(t testing.TB, src string) parens.Any {
+	form, err := reader.New(strings.NewReader(src)).One()
+	requireNoErr(t, err)
+	return form
 }
 
-func requireNoErr(t *testing.T, err error) {
+func requireNoErr(t testing.TB, err error) {
 	if err != nil {
 		t.Fatalf("unexpected error: %v", err)
 	}
@@ -0,0 +1,75 @@
+package parens
+
+import "sync"
+
+// NewInterner returns a new empty interning table.
+func NewInterner() *Interner {
+	return &Interner{names: map[string]string{}}
+}
+
+// Interner de-duplicates the names of symbols and keywords. All Symbol and
+// Keyword values interned using the same Interner share the backing string
+// data, so repeated names are stored only once and comparing two interned
+// values of the same name does not compare their bytes. Interning does not
+// make hashing cheaper: map lookups with interned keys still hash the whole
+// name. Interned values remain plain Symbol and Keyword values and compare
+// equal to values that are not interned.
+//
+// Names are never removed from an Interner. Scope the Interner to the data
+// it de-duplicates (e.g., one per Reader or per Env) instead of sharing one
+// across untrusted inputs. Interner is safe for concurrent use.
+type Interner struct {
+	mu    sync.RWMutex
+	names map[string]string
+}
+
+// Symbol returns the interned Symbol with the name.
+func (in *Interner) Symbol(name string) Symbol {
+	return Symbol(in.intern(name, nil))
+}
+
+// Keyword returns the interned Keyword with the name.
+func (in *Interner) Keyword(name string) Keyword {
+	return Keyword(in.intern(name, nil))
+}
+
+// Intern returns the interned string with the name. Unlike Symbol() and
+// Keyword(), Intern does not allocate if the name has been interned before.
+// name is not retained.
+func (in *Interner) Intern(name []byte) string {
+	return in.intern("", name)
+}
+
+// Len returns the number of names interned.
+func (in *Interner) Len() int {
+	in.mu.RLock()
+	defer in.mu.RUnlock()
+	return len(in.names)
+}
+
+func (in *Interner) intern(s string, b []byte) string {
+	in.mu.RLock()
+	var interned string
+	var found bool
+	if b != nil {
+		interned, found = in.names[string(b)] // does not allocate.
+	} else {
+		interned, found = in.names[s]
+	}
+	in.mu.RUnlock()
+	if found {
+		return interned
+	}
+
+	if b != nil {
+		s = string(b)
+	}
+
+	in.mu.Lock()
+	defer in.mu.Unlock()
+	if interned, found := in.names[s]; found {
+		return interned
+	}
+	in.names[s] = s
+	return s
+}
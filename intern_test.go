@@ -0,0 +1,59 @@
+package parens_test
+
+import (
+	"reflect"
+	"sync"
+	"testing"
+	"unsafe"
+
+	"github.com/spy16/parens"
+)
+
+func TestInterner(t *testing.T) {
+	in := parens.NewInterner()
+
+	name := []byte("foo")
+	sym := in.Symbol("foo")
+	kw := in.Keyword(string(name))
+	str := in.Intern(name)
+
+	if !sameData(string(sym), string(kw)) || !sameData(string(sym), str) {
+		t.Errorf("interned names do not share data")
+	}
+	assertEqual(t, parens.Symbol("foo"), sym)
+	assertEqual(t, parens.Keyword("foo"), kw)
+	assertEqual(t, 1, in.Len())
+
+	name[0] = 'b'
+	assertEqual(t, "foo", in.Intern([]byte("foo")))
+
+	allocs := testing.AllocsPerRun(100, func() { in.Intern(name[:3]) })
+	assertEqual(t, float64(0), allocs)
+
+	t.Run("Concurrent", func(t *testing.T) {
+		in := parens.NewInterner()
+
+		var wg sync.WaitGroup
+		results := make([]parens.Symbol, 10)
+		for i := range results {
+			wg.Add(1)
+			go func(i int) {
+				defer wg.Done()
+				results[i] = in.Symbol("shared")
+			}(i)
+		}
+		wg.Wait()
+
+		for _, sym := range results {
+			if !sameData(string(sym), string(results[0])) {
+				t.Errorf("interned names do not share data")
+			}
+		}
+	})
+}
+
+func sameData(a, b string) bool {
+	ha := (*reflect.StringHeader)(unsafe.Pointer(&a))
+	hb := (*reflect.StringHeader)(unsafe.Pointer(&b))
+	return ha.Data == hb.Data && ha.Len == hb.Len
+}
@@ -0,0 +1,102 @@
+package parens
+
+import (
+	"fmt"
+	"sync/atomic"
+)
+
+// Limits bound the resources an Env can use for evaluation. Zero value for
+// a field means the resource is not limited. Limits are shared with all the
+// envs forked from the env (e.g., by `go`), so the step and allocation
+// budgets bound the total usage across all of them.
+type Limits struct {
+	// MaxSteps is the max number of evaluation steps. Every invocation and
+	// every expression in a body (e.g., fn, let, do) is a step.
+	MaxSteps int64
+
+	// MaxAllocs is the max number of values (e.g., collections, strings and
+	// functions) constructed during evaluation.
+	MaxAllocs int64
+
+	// MaxCollectionSize is the max number of entries in a collection value
+	// constructed during evaluation.
+	MaxCollectionSize int
+
+	// MaxStringLen is the max length in bytes of a string value constructed
+	// during evaluation. Strings built by the core functions are checked
+	// wherever they end up. Strings built by other Invokables are checked
+	// only if the Invokable calls AllocString or returns the string itself
+	// (i.e., not inside a collection).
+	MaxStringLen int
+}
+
+// Alloc records the construction of a collection with given number of
+// entries. Returns ErrLimitExceeded if the allocation budget is used up or
+// the collection is too large. Invokable implementations that construct
+// collections should call Alloc to be accounted for in the Limits.
+func (env *Env) Alloc(size int) error {
+	if env.limits == nil {
+		return nil
+	}
+	if max := env.limits.MaxCollectionSize; max > 0 && size > max {
+		return limitErr("max collection size", max)
+	}
+	return env.limits.alloc()
+}
+
+// AllocString is same as Alloc but for a string of length n. Invokable
+// implementations that construct strings should call AllocString.
+func (env *Env) AllocString(n int) error {
+	if env.limits == nil {
+		return nil
+	}
+	if max := env.limits.MaxStringLen; max > 0 && n > max {
+		return limitErr("max string length", max)
+	}
+	return env.limits.alloc()
+}
+
+func (env *Env) step() error {
+	if env.limits == nil || env.limits.MaxSteps <= 0 {
+		return nil
+	}
+	if atomic.AddInt64(&env.limits.steps, 1) > env.limits.MaxSteps {
+		return limitErr("max steps", env.limits.MaxSteps)
+	}
+	return nil
+}
+
+// checkResult verifies the value returned by an invocation is within the
+// limits. This ensures Invokables not using AllocString cannot return
+// strings larger than allowed. Strings inside returned collections are not
+// checked.
+func (env *Env) checkResult(v Any) error {
+	if env.limits == nil || env.limits.MaxStringLen <= 0 {
+		return nil
+	}
+	if s, isStr := v.(String); isStr && len(s) > env.limits.MaxStringLen {
+		return limitErr("max string length", env.limits.MaxStringLen)
+	}
+	return nil
+}
+
+type limiter struct {
+	// usage counters are accessed atomically and must stay 64-bit aligned.
+	steps, allocs int64
+
+	Limits
+}
+
+func (l *limiter) alloc() error {
+	if l.MaxAllocs > 0 && atomic.AddInt64(&l.allocs, 1) > l.MaxAllocs {
+		return limitErr("max allocs", l.MaxAllocs)
+	}
+	return nil
+}
+
+func limitErr(limit string, max interface{}) error {
+	return Error{
+		Cause:   ErrLimitExceeded,
+		Message: fmt.Sprintf("%s (%v)", limit, max),
+	}
+}
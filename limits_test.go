@@ -0,0 +1,122 @@
+package parens_test
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/spy16/parens"
+)
+
+func TestWithLimits(t *testing.T) {
+	t.Parallel()
+
+	repeat := parens.GoFunc(func(_ *parens.Env, args ...parens.Any) (parens.Any, error) {
+		return parens.String(strings.Repeat("x", int(args[0].(parens.Int64)))), nil
+	})
+
+	table := []struct {
+		title   string
+		limits  parens.Limits
+		src     string
+		wantErr bool
+	}{
+		{
+			title:   "MaxSteps",
+			limits:  parens.Limits{MaxSteps: 100},
+			src:     `((fn forever () (forever)))`,
+			wantErr: true,
+		},
+		{
+			title:   "MaxDepth",
+			src:     `((fn forever () (forever)))`,
+			wantErr: true,
+		},
+		{
+			title:  "WithinMaxSteps",
+			limits: parens.Limits{MaxSteps: 100},
+			src:    `((fn () (hash-map) (hash-map)))`,
+		},
+		{
+			title:   "MaxAllocs",
+			limits:  parens.Limits{MaxAllocs: 2},
+			src:     `((fn () (hash-map) (hash-map) (hash-map)))`,
+			wantErr: true,
+		},
+		{
+			title:   "MaxCollectionSize",
+			limits:  parens.Limits{MaxCollectionSize: 1},
+			src:     `(hash-map :a 1 :b 2)`,
+			wantErr: true,
+		},
+		{
+			title:   "MaxCollectionSizeAssoc",
+			limits:  parens.Limits{MaxCollectionSize: 1},
+			src:     `(assoc (hash-map :a 1) :b 2)`,
+			wantErr: true,
+		},
+		{
+			title:   "MaxCollectionSizeVariadic",
+			limits:  parens.Limits{MaxCollectionSize: 1},
+			src:     `((fn (& more) more) 1 2)`,
+			wantErr: true,
+		},
+		{
+			title:  "ClosureCaptures",
+			limits: parens.Limits{MaxCollectionSize: 1},
+			src:    `(let (a 1 b 2) (fn () (hash-map a b)))`,
+		},
+		{
+			title:   "MaxStringLen",
+			limits:  parens.Limits{MaxStringLen: 8},
+			src:     `(repeat 10)`,
+			wantErr: true,
+		},
+		{
+			title:  "WithinMaxStringLen",
+			limits: parens.Limits{MaxStringLen: 8},
+			src:    `(repeat 8)`,
+		},
+		{
+			title:   "MaxStringLenInCollection",
+			limits:  parens.Limits{MaxStringLen: 2},
+			src:     `(re-seq #"x+" "a xxx")`,
+			wantErr: true,
+		},
+		{
+			title:   "MaxStringLenGroup",
+			limits:  parens.Limits{MaxStringLen: 2},
+			src:     `(re-find #"(x+)y" "xxxy")`,
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range table {
+		t.Run(tt.title, func(t *testing.T) {
+			_, err := evalBackends(t, tt.src, parens.WithCore(),
+				parens.WithLimits(tt.limits),
+				parens.WithMaxDepth(1000),
+				parens.WithGlobals(map[string]parens.Any{"repeat": repeat}, nil),
+			)
+			if tt.wantErr {
+				if !errors.Is(err, parens.ErrLimitExceeded) {
+					t.Errorf("expecting ErrLimitExceeded, got %v", err)
+				}
+			} else {
+				requireNoErr(t, err)
+			}
+		})
+	}
+
+	t.Run("SharedWithForks", func(t *testing.T) {
+		env := parens.New(parens.WithCore(), parens.WithLimits(parens.Limits{MaxAllocs: 1}))
+
+		_, err := env.Fork().Eval(readOne(t, `(hash-map)`))
+		requireNoErr(t, err)
+
+		_, err = env.Eval(readOne(t, `(hash-map)`))
+		if !errors.Is(err, parens.ErrLimitExceeded) {
+			t.Errorf("expecting ErrLimitExceeded, got %v", err)
+		}
+	})
+}
@@ -0,0 +1,343 @@
+package parens
+
+import (
+	"fmt"
+	"math"
+	"math/big"
+	"reflect"
+)
+
+// Ranks of the number types in the numeric tower. Arithmetic on numbers of
+// different types converts both to the type with the higher rank. Floats
+// are inexact and rank the highest so that inexactness is never lost.
+const (
+	rankInt64 = iota
+	rankBigInt
+	rankRatio
+	rankDecimal
+	rankFloat64
+)
+
+type numOp int
+
+const (
+	opAdd numOp = iota
+	opSub
+	opMul
+	opDiv
+)
+
+// arith applies the operator to the numbers following the numeric tower.
+// Int64 results that overflow are promoted to BigInt and division of exact
+// numbers returns a Ratio if the result is not an integer.
+func arith(op numOp, a, b Any) (Any, error) {
+	ra, err := numRank(a)
+	if err != nil {
+		return nil, err
+	}
+	rb, err := numRank(b)
+	if err != nil {
+		return nil, err
+	}
+
+	rank := ra
+	if rb > rank {
+		rank = rb
+	}
+
+	switch rank {
+	case rankInt64:
+		return arithInt64(op, int64(a.(Int64)), int64(b.(Int64)))
+
+	case rankBigInt:
+		return arithBigInt(op, toBigInt(a), toBigInt(b))
+
+	case rankRatio:
+		return arithRat(op, toRat(a), toRat(b))
+
+	case rankDecimal:
+		da, err := toDecimal(a)
+		if err != nil {
+			return nil, err
+		}
+		db, err := toDecimal(b)
+		if err != nil {
+			return nil, err
+		}
+		return arithDecimal(op, da, db)
+
+	default:
+		return arithFloat64(op, toFloat64(a), toFloat64(b)), nil
+	}
+}
+
+// compareNumbers returns -1, 0 or +1 depending on whether a is less than,
+// equal to or greater than b. Returns false if the numbers are not ordered
+// (i.e., one of them is NaN).
+func compareNumbers(a, b Any) (int, bool, error) {
+	ra, err := numRank(a)
+	if err != nil {
+		return 0, false, err
+	}
+	rb, err := numRank(b)
+	if err != nil {
+		return 0, false, err
+	}
+
+	switch {
+	case ra == rankInt64 && rb == rankInt64:
+		x, y := a.(Int64), b.(Int64)
+		if x < y {
+			return -1, true, nil
+		} else if x > y {
+			return 1, true, nil
+		}
+		return 0, true, nil
+
+	case ra == rankFloat64 || rb == rankFloat64:
+		x, y := toFloat64(a), toFloat64(b)
+		if math.IsNaN(x) || math.IsNaN(y) {
+			return 0, false, nil
+		} else if x < y {
+			return -1, true, nil
+		} else if x > y {
+			return 1, true, nil
+		}
+		return 0, true, nil
+
+	default:
+		return toRat(a).Cmp(toRat(b)), true, nil
+	}
+}
+
+func numRank(v Any) (int, error) {
+	switch v.(type) {
+	case Int64:
+		return rankInt64, nil
+	case BigInt:
+		return rankBigInt, nil
+	case Ratio:
+		return rankRatio, nil
+	case Decimal:
+		return rankDecimal, nil
+	case Float64:
+		return rankFloat64, nil
+	}
+	return 0, fmt.Errorf("expecting number, not '%s'", reflect.TypeOf(v))
+}
+
+func arithInt64(op numOp, x, y int64) (Any, error) {
+	switch op {
+	case opAdd:
+		if z := x + y; (x^z)&(y^z) >= 0 {
+			return Int64(z), nil
+		}
+
+	case opSub:
+		if z := x - y; (x^y)&(x^z) >= 0 {
+			return Int64(z), nil
+		}
+
+	case opMul:
+		if x == 0 || y == 0 {
+			return Int64(0), nil
+		}
+		if z := x * y; z/y == x && !(x == -1 && y == math.MinInt64) && !(y == -1 && x == math.MinInt64) {
+			return Int64(z), nil
+		}
+
+	case opDiv:
+		if y == 0 {
+			return nil, errDivideByZero()
+		} else if x%y != 0 {
+			return rational(big.NewRat(x, y)), nil
+		} else if !(x == math.MinInt64 && y == -1) {
+			return Int64(x / y), nil
+		}
+	}
+
+	// overflows int64.
+	return arithBigInt(op, big.NewInt(x), big.NewInt(y))
+}
+
+func arithBigInt(op numOp, x, y *big.Int) (Any, error) {
+	z := new(big.Int)
+	switch op {
+	case opAdd:
+		z.Add(x, y)
+	case opSub:
+		z.Sub(x, y)
+	case opMul:
+		z.Mul(x, y)
+	case opDiv:
+		if y.Sign() == 0 {
+			return nil, errDivideByZero()
+		}
+
+		var rem big.Int
+		if z.QuoRem(x, y, &rem); rem.Sign() != 0 {
+			return Ratio{Rat: new(big.Rat).SetFrac(x, y)}, nil
+		}
+	}
+	return BigInt{Int: z}, nil
+}
+
+func arithRat(op numOp, x, y *big.Rat) (Any, error) {
+	z := new(big.Rat)
+	switch op {
+	case opAdd:
+		z.Add(x, y)
+	case opSub:
+		z.Sub(x, y)
+	case opMul:
+		z.Mul(x, y)
+	case opDiv:
+		if y.Sign() == 0 {
+			return nil, errDivideByZero()
+		}
+		z.Quo(x, y)
+	}
+	return rational(z), nil
+}
+
+func arithDecimal(op numOp, x, y Decimal) (Any, error) {
+	switch op {
+	case opAdd, opSub:
+		scale := x.scale
+		if y.scale > scale {
+			scale = y.scale
+		}
+
+		z := new(big.Int)
+		if op == opAdd {
+			z.Add(x.rescale(scale), y.rescale(scale))
+		} else {
+			z.Sub(x.rescale(scale), y.rescale(scale))
+		}
+		return Decimal{unscaled: z, scale: scale}, nil
+
+	case opMul:
+		z := new(big.Int).Mul(x.unscaled, y.unscaled)
+		return Decimal{unscaled: z, scale: x.scale + y.scale}, nil
+
+	default:
+		if y.unscaled.Sign() == 0 {
+			return nil, errDivideByZero()
+		}
+		return ratDecimal(new(big.Rat).Quo(x.Rat(), y.Rat()))
+	}
+}
+
+func arithFloat64(op numOp, x, y float64) Any {
+	switch op {
+	case opAdd:
+		return Float64(x + y)
+	case opSub:
+		return Float64(x - y)
+	case opMul:
+		return Float64(x * y)
+	default:
+		return Float64(x / y)
+	}
+}
+
+// rational returns the rational number as a Ratio, or as an Int64 (or a
+// BigInt if it does not fit) if it is an integer.
+func rational(r *big.Rat) Any {
+	if !r.IsInt() {
+		return Ratio{Rat: r}
+	} else if num := r.Num(); num.IsInt64() {
+		return Int64(num.Int64())
+	}
+	return BigInt{Int: new(big.Int).Set(r.Num())}
+}
+
+// ratDecimal returns the exact decimal representation of the rational
+// number. Fails if the decimal expansion does not terminate (e.g., 1/3).
+func ratDecimal(r *big.Rat) (Decimal, error) {
+	den := new(big.Int).Set(r.Denom())
+	twos := int(den.TrailingZeroBits())
+	den.Rsh(den, uint(twos))
+
+	fives := 0
+	five, rem := big.NewInt(5), new(big.Int)
+	for {
+		q, m := new(big.Int).QuoRem(den, five, rem)
+		if m.Sign() != 0 {
+			break
+		}
+		den, fives = q, fives+1
+	}
+
+	if den.Cmp(big.NewInt(1)) != 0 {
+		return Decimal{}, Error{
+			Cause:   ErrArithmetic,
+			Message: fmt.Sprintf("non-terminating decimal expansion of %s", r.RatString()),
+		}
+	}
+
+	scale := twos
+	if fives > scale {
+		scale = fives
+	}
+	unscaled := new(big.Int).Mul(r.Num(), pow10(scale))
+	return Decimal{unscaled: unscaled.Quo(unscaled, r.Denom()), scale: scale}, nil
+}
+
+// rescale returns the unscaled value of the decimal at the given scale. The
+// scale must not be less than the scale of the decimal.
+func (d Decimal) rescale(scale int) *big.Int {
+	if scale == d.scale {
+		return d.unscaled
+	}
+	return new(big.Int).Mul(d.unscaled, pow10(scale-d.scale))
+}
+
+func toBigInt(v Any) *big.Int {
+	if bi, ok := v.(BigInt); ok {
+		return bi.Int
+	}
+	return big.NewInt(int64(v.(Int64)))
+}
+
+func toRat(v Any) *big.Rat {
+	switch n := v.(type) {
+	case Ratio:
+		return n.Rat
+	case Decimal:
+		return n.Rat()
+	default:
+		return new(big.Rat).SetInt(toBigInt(v))
+	}
+}
+
+func toDecimal(v Any) (Decimal, error) {
+	switch n := v.(type) {
+	case Decimal:
+		return n, nil
+	case Ratio:
+		return ratDecimal(n.Rat)
+	default:
+		return Decimal{unscaled: toBigInt(v)}, nil
+	}
+}
+
+func toFloat64(v Any) float64 {
+	switch n := v.(type) {
+	case Int64:
+		return float64(n)
+	case Float64:
+		return float64(n)
+	default:
+		f, _ := toRat(v).Float64()
+		return f
+	}
+}
+
+func pow10(n int) *big.Int {
+	return new(big.Int).Exp(big.NewInt(10), big.NewInt(int64(n)), nil)
+}
+
+func errDivideByZero() error {
+	return Error{Cause: ErrArithmetic, Message: "divide by zero"}
+}
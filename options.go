@@ -1,25 +1,54 @@
 package parens
 
+import "math/rand"
+
 // Option can be used with New() to customize initialization of Evaluator
 // Instance.
 type Option func(env *Env)
 
-// WithGlobals sets the global variables during initialisation. If factory
-// is nil, a mutex based concurrent map will be used.
+// WithGlobals sets the global variables during initialisation. Values that
+// are not *Var are wrapped in a Var without metadata. If factory is not nil,
+// it is used to create the global map and the globals set by the earlier
+// options are moved to the new map. If factory is nil, the existing global
+// map (or a new mutex based concurrent map) is used. NewCopyOnWriteMap can
+// be used as the factory for read-mostly globals.
 func WithGlobals(globals map[string]Any, factory func() ConcurrentMap) Option {
 	return func(env *Env) {
-		if factory == nil {
+		if factory == nil && env.globals == nil {
 			factory = newMutexMap
 		}
-		if env.globals == nil {
-			env.globals = factory()
+		if factory != nil {
+			m := factory()
+			if env.globals != nil {
+				env.globals.Range(func(key string, val Any) bool {
+					m.Store(key, val)
+					return true
+				})
+			}
+			env.globals = m
 		}
+
 		for k, v := range globals {
+			if _, isVar := v.(*Var); !isVar {
+				v = NewVar(k, v, nil)
+			}
 			env.globals.Store(k, v)
 		}
 	}
 }
 
+// WithCore binds the core library functions (e.g., `meta`, `hash-map`, `+`)
+// in the globals. Globals with the same names provided using WithGlobals()
+// take precedence.
+func WithCore() Option {
+	return func(env *Env) {
+		if env.globals == nil {
+			env.globals = newMutexMap()
+		}
+		bindCore(env)
+	}
+}
+
 // WithMaxDepth sets the max depth allowed for stack.  Panics if depth == 0.
 func WithMaxDepth(depth uint) Option {
 	if depth == 0 {
@@ -30,6 +59,60 @@ func WithMaxDepth(depth uint) Option {
 	}
 }
 
+// WithLimits sets the resource limits enforced during evaluation. Envs
+// forked from the env share the limits and the usage.
+func WithLimits(limits Limits) Option {
+	return func(env *Env) {
+		env.limits = &limiter{Limits: limits}
+	}
+}
+
+// WithPolicy sets the capability policy enforced during analysis and
+// evaluation. See Env.Restrict() for creating restricted views of an Env.
+func WithPolicy(p Policy) Option {
+	return func(env *Env) {
+		env.policies = []Policy{p}
+	}
+}
+
+// WithDeterministic enables the deterministic execution mode. In this mode,
+// the random number generator and clock of the Env are set as configured,
+// `go` forms are evaluated serially, and calls into native Go functions are
+// recorded or replayed using the Journal (if set).
+func WithDeterministic(d Deterministic) Option {
+	return func(env *Env) {
+		env.det = &determinism{
+			rand:    rand.New(&lockedSource{src: rand.NewSource(d.Seed)}),
+			now:     d.Now,
+			journal: d.Journal,
+		}
+	}
+}
+
+// WithTracer sets the Tracer to observe the analysis and evaluation. Envs
+// forked from the env use the same tracer.
+func WithTracer(t Tracer) Option {
+	return func(env *Env) {
+		env.tracer = t
+	}
+}
+
+// WithCoverage enables recording the coverage of the forms analyzed by the
+// env and the envs forked from it.
+func WithCoverage(c *Coverage) Option {
+	return func(env *Env) {
+		env.coverage = c
+	}
+}
+
+// WithDebugger attaches the debugger to the env and the envs forked from
+// it. See Debugger.
+func WithDebugger(d *Debugger) Option {
+	return func(env *Env) {
+		env.debugger = d
+	}
+}
+
 // WithExpander sets the macro Expander to be used by the p. If nil, a builtin
 // Expander will be used.
 func WithExpander(expander Expander) Option {
@@ -47,18 +130,28 @@ func WithExpander(expander Expander) Option {
 func WithAnalyzer(analyzer Analyzer) Option {
 	return func(env *Env) {
 		if analyzer == nil {
-			analyzer = &BuiltinAnalyzer{
-				SpecialForms: map[string]ParseSpecial{
-					"go":    parseGoExpr,
-					"def":   parseDefExpr,
-					"quote": parseQuoteExpr,
-				},
-			}
+			analyzer = newBuiltinAnalyzer()
 		}
 		env.analyzer = analyzer
 	}
 }
 
+func newBuiltinAnalyzer() *BuiltinAnalyzer {
+	return &BuiltinAnalyzer{
+		SpecialForms: map[string]ParseSpecial{
+			"go":      parseGoExpr,
+			"if":      parseIfExpr,
+			"def":     parseDefExpr,
+			"quote":   parseQuoteExpr,
+			"binding": parseBindingExpr,
+			"fn":      parseFnExpr,
+			"let":     parseLetExpr,
+			"var":     parseVarExpr,
+			"undef":   parseUndefExpr,
+		},
+	}
+}
+
 func withDefaults(opts []Option) []Option {
 	return append([]Option{
 		WithAnalyzer(nil),
@@ -15,14 +15,46 @@ var (
 
 	// ErrNotInvokable is returned by InvokeExpr when the target is not invokable.
 	ErrNotInvokable = errors.New("not invokable")
+
+	// ErrNotDynamic is returned when a binding is attempted for a var that
+	// is not dynamic.
+	ErrNotDynamic = errors.New("not a dynamic var")
+
+	// ErrArity is returned when an Invokable is invoked with wrong number
+	// of arguments.
+	ErrArity = errors.New("wrong number of args")
+
+	// ErrLimitExceeded is returned when the evaluation exceeds one of the
+	// Limits set on the Env.
+	ErrLimitExceeded = errors.New("limit exceeded")
+
+	// ErrForbidden is returned when a form uses a capability not permitted
+	// by the Policy of the Env.
+	ErrForbidden = errors.New("forbidden")
+
+	// ErrReplayMismatch is returned when the calls made while replaying a
+	// Journal do not match the recorded calls.
+	ErrReplayMismatch = errors.New("replay mismatch")
+
+	// ErrAborted is returned when the evaluation is aborted from the
+	// Debugger.
+	ErrAborted = errors.New("aborted")
+
+	// ErrArithmetic is returned when an arithmetic operation has no exact
+	// result (e.g., division by zero).
+	ErrArithmetic = errors.New("arithmetic error")
 )
 
 // New returns a new root context initialised based on given options.
 func New(opts ...Option) *Env {
-	env := &Env{ctx: context.Background(), globals: newMutexMap()}
+	env := &Env{ctx: context.Background()}
 	for _, opt := range withDefaults(opts) {
 		opt(env)
 	}
+
+	if env.globals == nil {
+		env.globals = newMutexMap()
+	}
 	return env
 }
 
@@ -35,6 +67,25 @@ type Any interface {
 	SExpr() (string, error)
 }
 
+// Annotated is implemented by values that carry a metadata map.
+type Annotated interface {
+	Any
+	Meta() Map
+}
+
+// Annotatable is implemented by values that can return a copy of themselves
+// with the metadata replaced.
+type Annotatable interface {
+	Annotated
+	WithMeta(meta Map) (Any, error)
+}
+
+// Positional is implemented by forms that know the position in the source
+// they were read from.
+type Positional interface {
+	Pos() Position
+}
+
 // Seq represents a sequence of values.
 type Seq interface {
 	Any
@@ -44,6 +95,15 @@ type Seq interface {
 	Conj(items ...Any) (Seq, error)
 }
 
+// Map represents an associative collection of key-value pairs.
+type Map interface {
+	Any
+	Count() (int, error)
+	HasKey(key Any) bool
+	EntryAt(key Any) (Any, bool)
+	Assoc(key, val Any) (Map, error)
+}
+
 // Analyzer implementation is responsible for performing syntax analysis
 // on given form.
 type Analyzer interface {
@@ -71,6 +131,23 @@ type Expr interface {
 	Eval(env *Env) (Any, error)
 }
 
+// Program is an analyzed form that can be evaluated repeatedly without the
+// cost of analysis. Use Env.Compile() to create a Program.
+type Program struct {
+	expr Expr
+}
+
+// Run evaluates the program against the env. Run can be called concurrently
+// from multiple goroutines as long as each uses its own env (See Env.Fork()).
+// Per-run values can be provided by binding dynamic vars using Env.Bind().
+// Non-dynamic globals referenced by the program are resolved at compile time.
+func (p Program) Run(env *Env) (Any, error) {
+	if p.expr == nil {
+		return Nil{}, nil
+	}
+	return p.expr.Eval(env)
+}
+
 // Error is returned by all parens operations. Cause indicates the underlying
 // error type. Use errors.Is() with Cause to check for specific errors.
 type Error struct {
@@ -90,3 +167,21 @@ func (e Error) Error() string {
 	}
 	return e.Message
 }
+
+// Position represents the positional information about a form read from
+// source.
+type Position struct {
+	File string
+	Ln   int
+	Col  int
+}
+
+// IsZero returns true if the position is not known.
+func (p Position) IsZero() bool { return p == Position{} }
+
+func (p Position) String() string {
+	if p.File == "" {
+		p.File = "<unknown>"
+	}
+	return fmt.Sprintf("%s:%d:%d", p.File, p.Ln, p.Col)
+}
@@ -9,6 +9,20 @@ import (
 func TestNew(t *testing.T) {
 	p := parens.New()
 	assertNotNil(t, p)
+	assertEqual(t, 0, len(p.Globals("")))
+}
+
+func TestWithCore(t *testing.T) {
+	env := parens.New(parens.WithCore(), parens.WithGlobals(map[string]parens.Any{
+		"get": parens.Int64(1),
+	}, nil))
+
+	if _, found := env.Var("hash-map"); !found {
+		t.Errorf("expecting hash-map to be bound")
+	}
+
+	get, _ := env.Var("get")
+	assertEqual(t, parens.Int64(1), get.Deref())
 }
 
 func assertNotNil(t *testing.T, v interface{}) {
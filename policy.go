@@ -0,0 +1,196 @@
+package parens
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+)
+
+var _ ConcurrentMap = (*overlayMap)(nil)
+
+// Policy restricts the capabilities available to the forms evaluated in an
+// Env. Use WithPolicy() to set the policy of an Env or Env.Restrict() to
+// create a restricted view of an existing Env. Capabilities that remove
+// globals (the `ns-unmap` global and the `undef` special form) are denied
+// unless the rule has an Allow entry matching them.
+type Policy struct {
+	// Globals controls the global vars that can be resolved, bound or
+	// defined.
+	Globals Rule
+
+	// SpecialForms controls the special forms (e.g., `go`, `def`) that can
+	// be used.
+	SpecialForms Rule
+
+	// Interop controls the globals holding native Go functions (Invokable
+	// values other than functions created using `fn`) that can be used.
+	// Names are the names of the globals (e.g., "hash-map") and are checked
+	// when the global is resolved, hence a denied function cannot be used
+	// through another name either.
+	Interop Rule
+}
+
+// Rule is an allow/deny list of names. A name is permitted if Allow is empty
+// or has an entry matching the name, and no entry in Deny matches it. An
+// entry ending with '*' matches all the names with the prefix.
+type Rule struct {
+	Allow []string
+	Deny  []string
+}
+
+// Permits returns true if the name is permitted by the rule.
+func (r Rule) Permits(name string) bool {
+	if len(r.Allow) > 0 && !matchAny(r.Allow, name) {
+		return false
+	}
+	return !matchAny(r.Deny, name)
+}
+
+// permitsStrict is same as Permits but requires the name to be allowed
+// explicitly if strict is set.
+func (r Rule) permitsStrict(name string, strict bool) bool {
+	if strict && !matchAny(r.Allow, name) {
+		return false
+	}
+	return r.Permits(name)
+}
+
+// Restrict returns a restricted view of the env for evaluating untrusted
+// forms (e.g., of one tenant). The view shares the globals of env, but the
+// globals defined or removed through the view are visible only to the view.
+// The policy applies in addition to the policies of env. The dynamic
+// bindings active in env are not visible to the view.
+func (env *Env) Restrict(p Policy) *Env {
+	view := env.Fork()
+	view.bindings = nil
+	view.globals = &overlayMap{root: env.globals, own: newMutexMap()}
+	view.policies = append(env.policies[:len(env.policies):len(env.policies)], p)
+	return view
+}
+
+func (env *Env) checkGlobal(name string) error {
+	for _, p := range env.policies {
+		if !p.Globals.permitsStrict(name, name == "ns-unmap") {
+			return forbiddenErr("global", name)
+		}
+	}
+	return nil
+}
+
+func (env *Env) checkSpecial(name string) error {
+	for _, p := range env.policies {
+		if !p.SpecialForms.permitsStrict(name, name == "undef") {
+			return forbiddenErr("special form", name)
+		}
+	}
+	return nil
+}
+
+// checkInterop verifies the value of the global with the name can be used
+// if it is a native Go function.
+func (env *Env) checkInterop(name string, val Any) error {
+	if len(env.policies) == 0 {
+		return nil
+	} else if _, isNative := val.(Invokable); !isNative {
+		return nil
+	} else if _, isFn := val.(*Fn); isFn {
+		return nil
+	}
+
+	for _, p := range env.policies {
+		if !p.Interop.Permits(name) {
+			return forbiddenErr("interop with", name)
+		}
+	}
+	return nil
+}
+
+func forbiddenErr(kind, name string) error {
+	return Error{
+		Cause:   ErrForbidden,
+		Message: fmt.Sprintf("%s '%s'", kind, name),
+	}
+}
+
+func matchAny(patterns []string, name string) bool {
+	for _, p := range patterns {
+		if strings.HasSuffix(p, "*") {
+			if strings.HasPrefix(name, p[:len(p)-1]) {
+				return true
+			}
+		} else if p == name {
+			return true
+		}
+	}
+	return false
+}
+
+// overlayMap is the ConcurrentMap of a restricted view. Entries stored or
+// deleted are recorded in the overlay without modifying the root.
+type overlayMap struct {
+	root ConcurrentMap
+	own  ConcurrentMap
+
+	mu     sync.RWMutex
+	hidden map[string]bool
+}
+
+func (om *overlayMap) Store(key string, val Any) {
+	om.mu.Lock()
+	delete(om.hidden, key)
+	om.own.Store(key, val)
+	om.mu.Unlock()
+}
+
+func (om *overlayMap) Load(key string) (Any, bool) {
+	if v, found := om.own.Load(key); found {
+		return v, true
+	}
+
+	om.mu.RLock()
+	hidden := om.hidden[key]
+	om.mu.RUnlock()
+	if hidden {
+		return nil, false
+	}
+	return om.root.Load(key)
+}
+
+func (om *overlayMap) Delete(key string) {
+	om.mu.Lock()
+	defer om.mu.Unlock()
+
+	if om.hidden == nil {
+		om.hidden = map[string]bool{}
+	}
+	om.hidden[key] = true
+	om.own.Delete(key)
+}
+
+func (om *overlayMap) Range(fn func(key string, val Any) bool) {
+	for key, val := range om.Map() {
+		if !fn(key, val) {
+			return
+		}
+	}
+}
+
+func (om *overlayMap) Map() map[string]Any {
+	om.mu.RLock()
+	defer om.mu.RUnlock()
+
+	m := om.root.Map()
+	for key := range om.hidden {
+		delete(m, key)
+	}
+	for key, val := range om.own.Map() {
+		m[key] = val
+	}
+	return m
+}
+
+// owns returns true if the entry for the key is stored in the overlay.
+func (om *overlayMap) owns(key string) bool {
+	_, found := om.own.Load(key)
+	return found
+}
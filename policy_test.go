@@ -0,0 +1,258 @@
+package parens_test
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/spy16/parens"
+)
+
+func TestRule_Permits(t *testing.T) {
+	t.Parallel()
+
+	rule := parens.Rule{Allow: []string{"str/*", "get"}, Deny: []string{"str/secret"}}
+	for name, want := range map[string]bool{
+		"get":        true,
+		"str/upper":  true,
+		"str/secret": false,
+		"assoc":      false,
+	} {
+		if got := rule.Permits(name); got != want {
+			t.Errorf("Permits(\"%s\") got = %t, want = %t", name, got, want)
+		}
+	}
+
+	if !(parens.Rule{}).Permits("anything") {
+		t.Errorf("empty rule must permit all names")
+	}
+}
+
+func TestWithPolicy(t *testing.T) {
+	t.Parallel()
+
+	table := []struct {
+		title   string
+		policy  parens.Policy
+		src     string
+		wantErr bool
+	}{
+		{
+			title:   "DeniedSpecialForm",
+			policy:  parens.Policy{SpecialForms: parens.Rule{Deny: []string{"go"}}},
+			src:     `(go (hash-map))`,
+			wantErr: true,
+		},
+		{
+			title:  "AllowedSpecialForm",
+			policy: parens.Policy{SpecialForms: parens.Rule{Deny: []string{"go"}}},
+			src:    `(def x 1)`,
+		},
+		{
+			title:   "DeniedGlobal",
+			policy:  parens.Policy{Globals: parens.Rule{Deny: []string{"ns-unmap"}}},
+			src:     `(ns-unmap 'x)`,
+			wantErr: true,
+		},
+		{
+			title:   "UnmapDeniedByDefault",
+			policy:  parens.Policy{},
+			src:     `(ns-unmap 'x)`,
+			wantErr: true,
+		},
+		{
+			title:  "UnmapAllowed",
+			policy: parens.Policy{Globals: parens.Rule{Allow: []string{"ns-unmap"}}},
+			src:    `(ns-unmap 'x)`,
+		},
+		{
+			title:   "UndefDeniedByDefault",
+			policy:  parens.Policy{},
+			src:     `(undef x)`,
+			wantErr: true,
+		},
+		{
+			title:  "UndefAllowed",
+			policy: parens.Policy{SpecialForms: parens.Rule{Allow: []string{"undef"}}},
+			src:    `(undef x)`,
+		},
+		{
+			title:   "DeniedGlobalNotAllowed",
+			policy:  parens.Policy{Globals: parens.Rule{Allow: []string{"hash-map"}}},
+			src:     `(get (hash-map) :a)`,
+			wantErr: true,
+		},
+		{
+			title:   "DeniedDef",
+			policy:  parens.Policy{Globals: parens.Rule{Allow: []string{"hash-map"}}},
+			src:     `(def x 1)`,
+			wantErr: true,
+		},
+		{
+			title:   "DeniedVar",
+			policy:  parens.Policy{Globals: parens.Rule{Deny: []string{"get"}}},
+			src:     `(var get)`,
+			wantErr: true,
+		},
+		{
+			title:  "LocalsAreNotGlobals",
+			policy: parens.Policy{Globals: parens.Rule{Allow: []string{"hash-map"}}},
+			src:    `((fn (get) get) 1)`,
+		},
+		{
+			title:   "DeniedInterop",
+			policy:  parens.Policy{Interop: parens.Rule{Deny: []string{"hash-map"}}},
+			src:     `(hash-map)`,
+			wantErr: true,
+		},
+		{
+			title:   "DeniedInteropAlias",
+			policy:  parens.Policy{Interop: parens.Rule{Deny: []string{"hash-map"}}},
+			src:     `((fn (f) (f)) hash-map)`,
+			wantErr: true,
+		},
+		{
+			title:  "AllowedInterop",
+			policy: parens.Policy{Interop: parens.Rule{Allow: []string{"hash-map"}}},
+			src:    `(def m (hash-map)) m`,
+		},
+		{
+			title:   "InteropNotAllowed",
+			policy:  parens.Policy{Interop: parens.Rule{Allow: []string{"hash-map"}}},
+			src:     `(get (hash-map) :a)`,
+			wantErr: true,
+		},
+		{
+			title:  "FnIsNotInterop",
+			policy: parens.Policy{Interop: parens.Rule{Deny: []string{"*"}}},
+			src:    `(def f (fn () 1)) (f)`,
+		},
+	}
+
+	for _, tt := range table {
+		t.Run(tt.title, func(t *testing.T) {
+			_, err := evalBackends(t, tt.src, parens.WithCore(), parens.WithPolicy(tt.policy))
+			if tt.wantErr {
+				if !errors.Is(err, parens.ErrForbidden) {
+					t.Errorf("expecting ErrForbidden, got %v", err)
+				}
+			} else {
+				requireNoErr(t, err)
+			}
+		})
+	}
+}
+
+func TestEnv_Restrict(t *testing.T) {
+	t.Parallel()
+
+	root := parens.New(parens.WithGlobals(map[string]parens.Any{
+		"shared":   parens.Int64(1),
+		"secret":   parens.String("s3cr3t"),
+		"*tenant*": parens.String("none"),
+	}, nil))
+
+	policy := parens.Policy{
+		Globals:      parens.Rule{Deny: []string{"secret"}},
+		SpecialForms: parens.Rule{Deny: []string{"go"}},
+	}
+	tenantA, tenantB := root.Restrict(policy), root.Restrict(policy)
+
+	t.Run("Forbidden", func(t *testing.T) {
+		_, err := tenantA.Eval(parens.Symbol("secret"))
+		if !errors.Is(err, parens.ErrForbidden) {
+			t.Errorf("expecting ErrForbidden, got %v", err)
+		}
+
+		if _, found := tenantA.Var("secret"); found {
+			t.Errorf("secret must not be visible in the view")
+		}
+		for _, v := range tenantA.Globals("") {
+			if v.Name() == "secret" {
+				t.Errorf("secret must not be listed in the view")
+			}
+		}
+	})
+
+	t.Run("AnalyzedByRoot", func(t *testing.T) {
+		for _, be := range backends {
+			root := parens.New(append(be.opts, parens.WithGlobals(map[string]parens.Any{
+				"secret": parens.String("s3cr3t"),
+			}, nil))...)
+			view := root.Restrict(policy)
+
+			expr, err := root.Analyze(parens.Symbol("secret"))
+			requireNoErr(t, err)
+			_, err = expr.Eval(view)
+			if !errors.Is(err, parens.ErrForbidden) {
+				t.Errorf("%s: expecting ErrForbidden, got %v", be.name, err)
+			}
+
+			prog, err := root.Compile(readOne(t, `((fn () secret))`))
+			requireNoErr(t, err)
+			_, err = prog.Run(view)
+			if !errors.Is(err, parens.ErrForbidden) {
+				t.Errorf("%s: expecting ErrForbidden, got %v", be.name, err)
+			}
+		}
+	})
+
+	t.Run("Isolated", func(t *testing.T) {
+		_, err := tenantA.Eval(readOne(t, `(def shared 2)`))
+		requireNoErr(t, err)
+		_, err = tenantA.Eval(readOne(t, `(def own :a)`))
+		requireNoErr(t, err)
+
+		res, err := tenantA.Eval(parens.Symbol("shared"))
+		requireNoErr(t, err)
+		assertEqual(t, parens.Int64(2), res)
+
+		for _, env := range []*parens.Env{root, tenantB} {
+			res, err := env.Eval(parens.Symbol("shared"))
+			requireNoErr(t, err)
+			assertEqual(t, parens.Int64(1), res)
+
+			if _, found := env.Var("own"); found {
+				t.Errorf("var defined in a view must not be visible to others")
+			}
+		}
+	})
+
+	t.Run("Undef", func(t *testing.T) {
+		view := root.Restrict(parens.Policy{})
+		if !view.Undef("shared") {
+			t.Errorf("Undef() expected to return true")
+		}
+
+		if _, found := view.Var("shared"); found {
+			t.Errorf("undefined var must not be visible in the view")
+		}
+		if _, found := root.Var("shared"); !found {
+			t.Errorf("undefined var in view must remain in the root")
+		}
+	})
+
+	t.Run("DynamicBindings", func(t *testing.T) {
+		env := root.Fork()
+		restore, err := env.Bind(map[string]parens.Any{"*tenant*": parens.String("admin")})
+		requireNoErr(t, err)
+		defer restore()
+
+		res, err := env.Restrict(policy).Eval(parens.Symbol("*tenant*"))
+		requireNoErr(t, err)
+		assertEqual(t, parens.String("none"), res)
+	})
+
+	t.Run("Nested", func(t *testing.T) {
+		view := tenantA.Restrict(parens.Policy{Globals: parens.Rule{Deny: []string{"shared"}}})
+
+		_, err := view.Eval(readOne(t, `(go 1)`))
+		if !errors.Is(err, parens.ErrForbidden) {
+			t.Errorf("expecting ErrForbidden, got %v", err)
+		}
+
+		_, err = view.Eval(parens.Symbol("shared"))
+		if !errors.Is(err, parens.ErrForbidden) {
+			t.Errorf("expecting ErrForbidden, got %v", err)
+		}
+	})
+}
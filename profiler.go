@@ -0,0 +1,251 @@
+package parens
+
+import (
+	"compress/gzip"
+	"io"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+var _ Tracer = (*Profiler)(nil)
+
+// NewProfiler returns a new Profiler that is not started.
+func NewProfiler() *Profiler {
+	return &Profiler{
+		samples: map[string]*profSample{},
+		open:    map[*Env][]time.Duration{},
+	}
+}
+
+// Profiler is a Tracer that records the number of calls and the time spent
+// in every call stack of Lisp functions. Stacks are identified using the
+// names of the functions invoked. Profiler must be set as the tracer of the
+// Env (See WithTracer()) and records only while it is started. The profile
+// can be written in the pprof format to be analyzed using `go tool pprof`.
+type Profiler struct {
+	NopTracer
+
+	mu      sync.Mutex
+	active  bool
+	started time.Time
+	elapsed time.Duration
+	samples map[string]*profSample
+
+	// time spent in the callees of the active calls of every env.
+	open map[*Env][]time.Duration
+}
+
+type profSample struct {
+	stack []string // leaf first.
+	calls int64
+	self  time.Duration
+}
+
+// Start starts recording the calls.
+func (p *Profiler) Start() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if !p.active {
+		p.active = true
+		p.started = time.Now()
+	}
+}
+
+// Stop stops recording the calls. Samples recorded so far are retained.
+func (p *Profiler) Stop() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.active {
+		p.active = false
+		p.elapsed += time.Since(p.started)
+		p.open = map[*Env][]time.Duration{}
+	}
+}
+
+// Reset discards all the samples recorded.
+func (p *Profiler) Reset() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.samples = map[string]*profSample{}
+	p.elapsed = 0
+	p.started = time.Now()
+}
+
+// InvokeStart records the start of a call.
+func (p *Profiler) InvokeStart(env *Env, _ string, _ []Any) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.active {
+		p.open[env] = append(p.open[env], 0)
+	}
+}
+
+// InvokeEnd records the call with the time spent in it excluding the time
+// spent in the functions it called. Calls that started before the profiler
+// was started are not recorded.
+func (p *Profiler) InvokeEnd(env *Env, name string, _ []Any, _ Any, _ error, dur time.Duration) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	open := p.open[env]
+	if !p.active || len(open) == 0 {
+		// call started before the profiler.
+		return
+	}
+
+	self := dur - open[len(open)-1]
+	open = open[:len(open)-1]
+	if len(open) > 0 {
+		open[len(open)-1] += dur
+		p.open[env] = open
+	} else {
+		delete(p.open, env)
+	}
+
+	stack := make([]string, 0, len(env.stack)+1)
+	stack = append(stack, name)
+	for i := len(env.stack) - 1; i >= 0; i-- {
+		stack = append(stack, env.stack[i].Name)
+	}
+
+	key := strings.Join(stack, "\x00")
+	s, found := p.samples[key]
+	if !found {
+		s = &profSample{stack: stack}
+		p.samples[key] = s
+	}
+	s.calls++
+	s.self += self
+}
+
+// WriteProfile writes the profile in the gzip compressed protobuf format of
+// pprof. Profile has two sample types: the number of calls and the time
+// spent (in nanoseconds) in every call stack.
+func (p *Profiler) WriteProfile(w io.Writer) error {
+	p.mu.Lock()
+	samples := make([]*profSample, 0, len(p.samples))
+	for _, s := range p.samples {
+		samples = append(samples, s)
+	}
+	started, elapsed := p.started, p.elapsed
+	if p.active {
+		elapsed += time.Since(p.started)
+	}
+	p.mu.Unlock()
+
+	sort.Slice(samples, func(i, j int) bool {
+		return strings.Join(samples[i].stack, "\x00") < strings.Join(samples[j].stack, "\x00")
+	})
+
+	var b protoBuffer
+	strs := map[string]int64{"": 0}
+	table := []string{""}
+	str := func(s string) int64 {
+		if idx, found := strs[s]; found {
+			return idx
+		}
+		strs[s] = int64(len(table))
+		table = append(table, s)
+		return strs[s]
+	}
+
+	valueType := func(typ, unit string) []byte {
+		var vt protoBuffer
+		vt.int64(1, str(typ))
+		vt.int64(2, str(unit))
+		return vt.buf
+	}
+	b.bytes(1, valueType("calls", "count"))
+	b.bytes(1, valueType("time", "nanoseconds"))
+
+	// every function has exactly one location with the same id.
+	funcs := map[string]uint64{}
+	var names []string
+	for _, s := range samples {
+		var sample protoBuffer
+		ids := make([]uint64, len(s.stack))
+		for i, name := range s.stack {
+			if _, found := funcs[name]; !found {
+				funcs[name] = uint64(len(funcs) + 1)
+				names = append(names, name)
+			}
+			ids[i] = funcs[name]
+		}
+		sample.packedUint64(1, ids)
+		sample.packedUint64(2, []uint64{uint64(s.calls), uint64(s.self.Nanoseconds())})
+		b.bytes(2, sample.buf)
+	}
+
+	for i := range names {
+		var line, loc protoBuffer
+		line.uint64(1, uint64(i+1))
+		loc.uint64(1, uint64(i+1))
+		loc.bytes(4, line.buf)
+		b.bytes(4, loc.buf)
+	}
+
+	for i, name := range names {
+		var fn protoBuffer
+		fn.uint64(1, uint64(i+1))
+		fn.int64(2, str(name))
+		fn.int64(3, str(name))
+		b.bytes(5, fn.buf)
+	}
+
+	periodType := valueType("calls", "count")
+	for _, s := range table {
+		b.bytes(6, []byte(s))
+	}
+	b.int64(9, started.UnixNano())
+	b.int64(10, elapsed.Nanoseconds())
+	b.bytes(11, periodType)
+	b.int64(12, 1)
+
+	zw := gzip.NewWriter(w)
+	if _, err := zw.Write(b.buf); err != nil {
+		return err
+	}
+	return zw.Close()
+}
+
+// protoBuffer implements the subset of protobuf wire encoding required to
+// write pprof profiles.
+type protoBuffer struct{ buf []byte }
+
+func (pb *protoBuffer) varint(v uint64) {
+	for v >= 0x80 {
+		pb.buf = append(pb.buf, byte(v)|0x80)
+		v >>= 7
+	}
+	pb.buf = append(pb.buf, byte(v))
+}
+
+func (pb *protoBuffer) uint64(field int, v uint64) {
+	if v == 0 {
+		return
+	}
+	pb.varint(uint64(field) << 3)
+	pb.varint(v)
+}
+
+func (pb *protoBuffer) int64(field int, v int64) { pb.uint64(field, uint64(v)) }
+
+func (pb *protoBuffer) bytes(field int, b []byte) {
+	pb.varint(uint64(field)<<3 | 2)
+	pb.varint(uint64(len(b)))
+	pb.buf = append(pb.buf, b...)
+}
+
+func (pb *protoBuffer) packedUint64(field int, vs []uint64) {
+	var packed protoBuffer
+	for _, v := range vs {
+		packed.varint(v)
+	}
+	pb.bytes(field, packed.buf)
+}
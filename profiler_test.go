@@ -0,0 +1,178 @@
+package parens_test
+
+import (
+	"bytes"
+	"compress/gzip"
+	"io/ioutil"
+	"strings"
+	"testing"
+
+	"github.com/spy16/parens"
+)
+
+func TestProfiler(t *testing.T) {
+	t.Parallel()
+
+	forEachBackend(t, func(t *testing.T, opts ...parens.Option) {
+		p := parens.NewProfiler()
+		env := parens.New(append(opts, parens.WithCore(), parens.WithTracer(p))...)
+
+		_, err := env.Eval(readOne(t, `(def square (fn (x) (hash-map :x x)))`))
+		requireNoErr(t, err)
+
+		_, err = env.Eval(readOne(t, `(square 1)`))
+		requireNoErr(t, err)
+
+		p.Start()
+		_, err = env.Eval(readOne(t, `((fn compute (x) (square x) (square x)) 1)`))
+		requireNoErr(t, err)
+		p.Stop()
+
+		_, err = env.Eval(readOne(t, `(hash-map :ignored 1)`))
+		requireNoErr(t, err)
+
+		var buf bytes.Buffer
+		requireNoErr(t, p.WriteProfile(&buf))
+
+		prof := decodeProfile(t, buf.Bytes())
+		assertEqual(t, []string{"calls/count", "time/nanoseconds"}, prof.sampleTypes)
+		compute := "(fn compute (x) (square x) (square x))"
+		assertEqual(t, map[string]int64{
+			compute:                          1,
+			"square < " + compute:            2,
+			"hash-map < square < " + compute: 2,
+		}, prof.calls)
+	})
+}
+
+// profile is the subset of a decoded pprof profile used by the tests.
+type profile struct {
+	sampleTypes []string
+	calls       map[string]int64 // calls by the stack (leaf first).
+}
+
+// decodeProfile decodes the gzip compressed pprof protobuf message.
+func decodeProfile(t *testing.T, data []byte) profile {
+	zr, err := gzip.NewReader(bytes.NewReader(data))
+	requireNoErr(t, err)
+	raw, err := ioutil.ReadAll(zr)
+	requireNoErr(t, err)
+
+	var strs []string
+	var types, samples [][]byte
+	locFuncs, funcNames := map[uint64]uint64{}, map[uint64]uint64{}
+	walkProto(t, raw, func(field int, v uint64, b []byte) {
+		switch field {
+		case 1:
+			types = append(types, b)
+		case 2:
+			samples = append(samples, b)
+		case 4: // location: id=1, line=4 (function_id=1).
+			var id, fn uint64
+			walkProto(t, b, func(field int, v uint64, b []byte) {
+				if field == 1 {
+					id = v
+				} else if field == 4 {
+					walkProto(t, b, func(field int, v uint64, _ []byte) {
+						if field == 1 {
+							fn = v
+						}
+					})
+				}
+			})
+			locFuncs[id] = fn
+		case 5: // function: id=1, name=2.
+			var id, name uint64
+			walkProto(t, b, func(field int, v uint64, _ []byte) {
+				if field == 1 {
+					id = v
+				} else if field == 2 {
+					name = v
+				}
+			})
+			funcNames[id] = name
+		case 6:
+			strs = append(strs, string(b))
+		}
+	})
+
+	prof := profile{calls: map[string]int64{}}
+	for _, vt := range types {
+		var typ, unit uint64
+		walkProto(t, vt, func(field int, v uint64, _ []byte) {
+			if field == 1 {
+				typ = v
+			} else if field == 2 {
+				unit = v
+			}
+		})
+		prof.sampleTypes = append(prof.sampleTypes, strs[typ]+"/"+strs[unit])
+	}
+
+	for _, s := range samples {
+		var stack []string
+		var values []uint64
+		walkProto(t, s, func(field int, _ uint64, b []byte) {
+			packed := unpackVarints(t, b)
+			if field == 1 {
+				for _, loc := range packed {
+					stack = append(stack, strs[funcNames[locFuncs[loc]]])
+				}
+			} else if field == 2 {
+				values = packed
+			}
+		})
+		prof.calls[strings.Join(stack, " < ")] += int64(values[0])
+	}
+	return prof
+}
+
+// walkProto calls fn for every field of the protobuf message. v is set for
+// the varint fields and b for the length delimited fields.
+func walkProto(t *testing.T, msg []byte, fn func(field int, v uint64, b []byte)) {
+	for len(msg) > 0 {
+		key, n := decodeVarint(t, msg)
+		msg = msg[n:]
+
+		switch key & 7 {
+		case 0:
+			v, n := decodeVarint(t, msg)
+			msg = msg[n:]
+			fn(int(key>>3), v, nil)
+
+		case 2:
+			l, n := decodeVarint(t, msg)
+			msg = msg[n:]
+			if uint64(len(msg)) < l {
+				t.Fatalf("truncated protobuf message")
+			}
+			fn(int(key>>3), 0, msg[:l])
+			msg = msg[l:]
+
+		default:
+			t.Fatalf("unexpected wire type %d", key&7)
+		}
+	}
+}
+
+func unpackVarints(t *testing.T, b []byte) []uint64 {
+	var vs []uint64
+	for len(b) > 0 {
+		v, n := decodeVarint(t, b)
+		vs = append(vs, v)
+		b = b[n:]
+	}
+	return vs
+}
+
+func decodeVarint(t *testing.T, b []byte) (uint64, int) {
+	var v uint64
+	for i := 0; i < len(b) && i < 10; i++ {
+		v |= uint64(b[i]&0x7f) << (7 * uint(i))
+		if b[i] < 0x80 {
+			return v, i + 1
+		}
+	}
+	t.Fatalf("invalid varint")
+	return 0, 0
+}
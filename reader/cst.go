@@ -0,0 +1,410 @@
+package reader
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"io/ioutil"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/spy16/parens"
+)
+
+// NodeKind represents the kind of a node of the concrete syntax tree.
+type NodeKind int
+
+// Kinds of the nodes of the concrete syntax tree.
+const (
+	// NodeRoot is the root node containing all the nodes of the source.
+	NodeRoot NodeKind = iota
+
+	// NodeWhitespace is a run of whitespace characters (including ',').
+	NodeWhitespace
+
+	// NodeComment is a line comment starting with ';'. The terminating
+	// newline is not part of the comment.
+	NodeComment
+
+	// NodeToken is an atom: a number, symbol, keyword, string, character,
+	// regex (`#"..."`) or symbolic value (`##Inf`).
+	NodeToken
+
+	// NodeList is a list `(...)`, an anonymous function literal `#(...)` or
+	// a reader conditional `#?(...)`/`#?@(...)`.
+	NodeList
+
+	// NodeMap is a map literal `{...}`.
+	NodeMap
+
+	// NodePrefixed is a form prefixed by a quote (`'`, "`", `~`), a tag
+	// (e.g., `#inst`) or metadata (`^`). Metadata nodes have two forms, the
+	// metadata and the annotated form.
+	NodePrefixed
+
+	// NodeDiscard is a form discarded with `#_`.
+	NodeDiscard
+)
+
+func (k NodeKind) String() string {
+	switch k {
+	case NodeRoot:
+		return "root"
+	case NodeWhitespace:
+		return "whitespace"
+	case NodeComment:
+		return "comment"
+	case NodeToken:
+		return "token"
+	case NodeList:
+		return "list"
+	case NodeMap:
+		return "map"
+	case NodePrefixed:
+		return "prefixed"
+	case NodeDiscard:
+		return "discard"
+	}
+	return "unknown"
+}
+
+// ReadCST reads the source into a lossless concrete syntax tree. Unlike the
+// Reader, comments, whitespace and discarded forms are retained as nodes and
+// printing the tree (See Node.String()) reproduces the source byte for byte.
+// The tree follows the syntax of the default read table. Tokens are not
+// validated, use Node.Form() to read the form of a node.
+func ReadCST(r io.Reader) (*Node, error) {
+	src, err := ioutil.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+
+	p := cstParser{
+		src:  src,
+		file: inferFileName(r),
+		rd:   New(bytes.NewReader(nil)),
+		ln:   1,
+	}
+
+	root := &Node{Kind: NodeRoot, Begin: p.next(), End: p.next()}
+	if root.Children, err = p.nodes(0); err != nil {
+		return nil, err
+	} else if len(src) > 0 {
+		root.End = p.last
+	}
+	return root, nil
+}
+
+// Node represents a node of the concrete syntax tree read by ReadCST().
+type Node struct {
+	Kind NodeKind
+
+	// Text is the source text of whitespace, comment and token nodes and
+	// the opening text of other nodes (e.g., `(`, `#?@(`, `'`, `#inst`).
+	Text string
+
+	// Close is the closing delimiter of list and map nodes.
+	Close string
+
+	// Children are the nodes within the node in the order of the source.
+	Children []*Node
+
+	// Begin and End are the positions of the first and the last character
+	// of the node. Both are same as the position of the next character for
+	// empty nodes.
+	Begin, End Position
+}
+
+// String returns the source text of the node.
+func (n *Node) String() string {
+	var b strings.Builder
+	_, _ = n.WriteTo(&b)
+	return b.String()
+}
+
+// WriteTo writes the source text of the node to w.
+func (n *Node) WriteTo(w io.Writer) (int64, error) {
+	var total int64
+	write := func(s string) error {
+		c, err := io.WriteString(w, s)
+		total += int64(c)
+		return err
+	}
+
+	if err := write(n.Text); err != nil {
+		return total, err
+	}
+	for _, child := range n.Children {
+		c, err := child.WriteTo(w)
+		total += c
+		if err != nil {
+			return total, err
+		}
+	}
+	return total, write(n.Close)
+}
+
+// Form reads the form represented by the node using a Reader created with
+// the given options. Returns io.EOF for whitespace, comment and discard
+// nodes.
+func (n *Node) Form(opts ...Option) (parens.Any, error) {
+	return New(strings.NewReader(n.String()), opts...).One()
+}
+
+// IsTrivia returns true if the node does not represent a form. i.e., the
+// node is whitespace, a comment or a discarded form.
+func (n *Node) IsTrivia() bool {
+	return n.Kind == NodeWhitespace || n.Kind == NodeComment || n.Kind == NodeDiscard
+}
+
+type cstParser struct {
+	src  []byte
+	off  int
+	file string
+	rd   *Reader // provides the default read table.
+
+	ln, col int      // line and column of the next character.
+	last    Position // position of the last character read.
+}
+
+// mark is the beginning of a node.
+type mark struct {
+	off int
+	pos Position
+}
+
+// nodes reads the nodes until the closing delimiter (or EOF if close is 0)
+// and returns them. The closing delimiter is not consumed. Returns ErrEOF if
+// the stream ends before the closing delimiter.
+func (p *cstParser) nodes(close rune) ([]*Node, error) {
+	var nodes []*Node
+	for {
+		r, _ := p.peek()
+		if r < 0 {
+			if close == 0 {
+				return nodes, nil
+			}
+			return nil, ErrEOF
+		} else if r == close {
+			return nodes, nil
+		}
+
+		node, err := p.node()
+		if err != nil {
+			return nil, err
+		}
+		nodes = append(nodes, node)
+	}
+}
+
+// node reads the next node.
+func (p *cstParser) node() (*Node, error) {
+	begin := mark{off: p.off, pos: p.next()}
+	r, _ := p.peek()
+
+	switch {
+	case isSpace(r):
+		return p.leaf(NodeWhitespace, begin, func(r rune) bool { return isSpace(r) }), nil
+
+	case r == ';':
+		return p.leaf(NodeComment, begin, func(r rune) bool { return r != '\n' }), nil
+
+	case r == ')' || r == '}':
+		p.read()
+		return nil, Error{Cause: ErrUnmatchedDelimiter, Rune: r, Begin: begin.pos, End: begin.pos}
+
+	case r == '(':
+		return p.container(NodeList, p.read(), ')', begin)
+
+	case r == '{':
+		return p.container(NodeMap, p.read(), '}', begin)
+
+	case r == '"':
+		p.read()
+		return p.quoted(begin)
+
+	case r == '\\':
+		p.read()
+		if r, _ := p.peek(); r < 0 {
+			return nil, Error{Cause: ErrEOF, Form: "\\", Begin: begin.pos, End: p.last}
+		}
+		p.read()
+		return p.token(begin), nil
+
+	case r == '\'' || r == '~' || r == '`':
+		return p.prefixed(NodePrefixed, p.read(), 1, begin)
+
+	case r == '^':
+		return p.prefixed(NodePrefixed, p.read(), 2, begin)
+
+	case r == dispatchTrigger:
+		return p.dispatch(begin)
+	}
+
+	p.read()
+	return p.token(begin), nil
+}
+
+func (p *cstParser) dispatch(begin mark) (*Node, error) {
+	p.read()
+	r, _ := p.peek()
+
+	switch {
+	case r == '_':
+		p.read()
+		return p.prefixed(NodeDiscard, "#_", 1, begin)
+
+	case r == '(':
+		p.read()
+		return p.container(NodeList, "#(", ')', begin)
+
+	case r == '"':
+		p.read()
+		return p.quoted(begin)
+
+	case r == '#':
+		p.read()
+		return p.token(begin), nil
+
+	case r == '?':
+		p.read()
+		open := "#?"
+		if r, _ := p.peek(); r == '@' {
+			open += p.read()
+		}
+
+		if r, _ := p.peek(); r != '(' {
+			err := errors.New("reader conditional body must be a list")
+			return nil, Error{Cause: err, Form: open, Begin: begin.pos, End: p.last}
+		}
+		return p.container(NodeList, open+p.read(), ')', begin)
+
+	case r >= 0 && unicode.IsLetter(r):
+		tag := p.token(begin)
+		return p.prefixed(NodePrefixed, tag.Text, 1, begin)
+	}
+
+	// '#' followed by any other character is a symbol.
+	return p.token(begin), nil
+}
+
+// container reads the nodes of a list or map until the closing delimiter.
+func (p *cstParser) container(kind NodeKind, open string, close rune, begin mark) (*Node, error) {
+	children, err := p.nodes(close)
+	if err == ErrEOF {
+		return nil, Error{Cause: ErrEOF, Form: open, Begin: begin.pos, End: p.last}
+	} else if err != nil {
+		return nil, err
+	}
+
+	node := &Node{Kind: kind, Text: open, Children: children, Begin: begin.pos}
+	node.Close = p.read()
+	node.End = p.last
+	return node, nil
+}
+
+// prefixed reads the trivia nodes and n forms following the prefix.
+func (p *cstParser) prefixed(kind NodeKind, prefix string, n int, begin mark) (*Node, error) {
+	node := &Node{Kind: kind, Text: prefix, Begin: begin.pos}
+	for n > 0 {
+		r, _ := p.peek()
+		if r < 0 {
+			return nil, Error{Cause: ErrEOF, Form: prefix, Begin: begin.pos, End: p.last}
+		}
+
+		child, err := p.node()
+		if err != nil {
+			return nil, err
+		}
+		node.Children = append(node.Children, child)
+
+		if !child.IsTrivia() {
+			n--
+		}
+	}
+	node.End = p.last
+	return node, nil
+}
+
+// quoted reads the rest of a string or regex token after the opening quote.
+func (p *cstParser) quoted(begin mark) (*Node, error) {
+	for {
+		r, _ := p.peek()
+		if r < 0 {
+			form := string(p.src[begin.off:p.off])
+			return nil, Error{Cause: ErrEOF, Form: form, Begin: begin.pos, End: p.last}
+		}
+		p.read()
+
+		if r == '"' {
+			break
+		} else if r == '\\' {
+			if r, _ := p.peek(); r >= 0 {
+				p.read()
+			}
+		}
+	}
+
+	return p.leafNode(NodeToken, begin), nil
+}
+
+// token reads the rest of the token until a terminal character.
+func (p *cstParser) token(begin mark) *Node {
+	for {
+		r, _ := p.peek()
+		if r < 0 || p.rd.IsTerminal(r) {
+			break
+		}
+		p.read()
+	}
+
+	return p.leafNode(NodeToken, begin)
+}
+
+// leaf reads the characters matching the predicate into a node.
+func (p *cstParser) leaf(kind NodeKind, begin mark, match func(r rune) bool) *Node {
+	for {
+		r, _ := p.peek()
+		if r < 0 || !match(r) {
+			break
+		}
+		p.read()
+	}
+	return p.leafNode(kind, begin)
+}
+
+// leafNode returns a node with the source text read since the mark.
+func (p *cstParser) leafNode(kind NodeKind, begin mark) *Node {
+	return &Node{Kind: kind, Text: string(p.src[begin.off:p.off]), Begin: begin.pos, End: p.last}
+}
+
+// peek returns the next rune and its size without consuming it. Returns -1
+// at EOF. Invalid UTF-8 bytes are returned as utf8.RuneError of size 1.
+func (p *cstParser) peek() (rune, int) {
+	if p.off >= len(p.src) {
+		return -1, 0
+	}
+	return utf8.DecodeRune(p.src[p.off:])
+}
+
+// read consumes the next rune and returns its source text.
+func (p *cstParser) read() string {
+	r, size := p.peek()
+	text := string(p.src[p.off : p.off+size])
+	p.off += size
+
+	p.last = p.next()
+	if r == '\n' {
+		p.ln++
+		p.col = 0
+	} else {
+		p.col++
+	}
+	return text
+}
+
+// next returns the position of the next character.
+func (p *cstParser) next() Position {
+	return Position{File: p.file, Ln: p.ln, Col: p.col + 1}
+}
@@ -22,6 +22,14 @@ var (
 	// ErrNumberFormat is returned when a reader macro encounters a illegally
 	// formatted numerical form.
 	ErrNumberFormat = errors.New("invalid number format")
+
+	// ErrInvalidEscape is returned when a string literal contains a malformed
+	// or unknown escape sequence.
+	ErrInvalidEscape = errors.New("invalid escape sequence")
+
+	// ErrUnknownTag is returned when a tagged literal has no data reader for
+	// its tag. See WithUnknownTags().
+	ErrUnknownTag = errors.New("no data reader for tag")
 )
 
 // Error is returned by all parens operations. Cause indicates the underlying
@@ -4,8 +4,14 @@ import (
 	"errors"
 	"fmt"
 	"io"
+	"math"
+	"math/big"
+	"reflect"
 	"strconv"
 	"strings"
+	"time"
+	"unicode"
+	"unicode/utf16"
 
 	"github.com/spy16/parens"
 )
@@ -14,6 +20,11 @@ import (
 // or customize behavior of the reader.
 type Macro func(rd *Reader, init rune) (parens.Any, error)
 
+// DataReader converts the form following the tag of a tagged literal (e.g.,
+// the string in `#inst "2020-09-09T00:00:00Z"`) into the value represented
+// by the literal. See WithDataReaders().
+type DataReader func(form parens.Any) (parens.Any, error)
+
 // // TODO(enhancement):  implement parens.Set
 // // SetReader implements the reader macro for reading set from source.
 // func SetReader(setEnd rune, factory func() parens.Set) Macro {
@@ -92,14 +103,60 @@ func readNumber(rd *Reader, init rune) (parens.Any, error) {
 		return nil, err
 	}
 
+	unsigned := strings.TrimLeft(numStr, "+-")
+	isHex := strings.HasPrefix(unsigned, "0x") || strings.HasPrefix(unsigned, "0X")
+	isRadix := !isHex && strings.ContainsRune(numStr, 'r')
+
+	if strings.ContainsRune(numStr, '_') {
+		digits := isDecimalDigit
+		if isHex {
+			digits = isHexDigit
+		} else if isRadix {
+			digits = isAlphaNum
+		}
+
+		if numStr, err = stripSeparators(numStr, digits); err != nil {
+			return nil, rd.annotateErr(err, beginPos, numStr)
+		}
+	}
+
 	decimalPoint := strings.ContainsRune(numStr, '.')
-	isRadix := strings.ContainsRune(numStr, 'r')
-	isScientific := strings.ContainsRune(numStr, 'e')
+	isScientific := !isHex && !isRadix && strings.ContainsRune(numStr, 'e')
+	isRatio := strings.ContainsRune(numStr, '/')
+
+	var suffix byte
+	if !isRadix {
+		switch numStr[len(numStr)-1] {
+		case 'N', 'M':
+			suffix = numStr[len(numStr)-1]
+		}
+	}
 
 	switch {
-	case isRadix && (decimalPoint || isScientific):
+	case isRadix && (decimalPoint || isRatio):
 		return nil, rd.annotateErr(ErrNumberFormat, beginPos, numStr)
 
+	case isRatio:
+		v, err := parseRatio(numStr)
+		if err != nil {
+			return nil, rd.annotateErr(err, beginPos, numStr)
+		}
+		return v, nil
+
+	case suffix == 'M':
+		v, err := parens.ParseDecimal(numStr[:len(numStr)-1])
+		if err != nil {
+			return nil, rd.annotateErr(ErrNumberFormat, beginPos, numStr)
+		}
+		return v, nil
+
+	case suffix == 'N':
+		v, ok := new(big.Int).SetString(numStr[:len(numStr)-1], 0)
+		if !ok {
+			return nil, rd.annotateErr(ErrNumberFormat, beginPos, numStr)
+		}
+		return parens.BigInt{Int: v}, nil
+
 	case isScientific:
 		v, err := parseScientific(numStr)
 		if err != nil {
@@ -123,6 +180,12 @@ func readNumber(rd *Reader, init rune) (parens.Any, error) {
 
 	default:
 		v, err := strconv.ParseInt(numStr, 0, 64)
+		if errors.Is(err, strconv.ErrRange) {
+			// promote to BigInt instead of failing.
+			if bi, ok := new(big.Int).SetString(numStr, 0); ok {
+				return parens.BigInt{Int: bi}, nil
+			}
+		}
 		if err != nil {
 			return nil, rd.annotateErr(ErrNumberFormat, beginPos, numStr)
 		}
@@ -134,12 +197,12 @@ func readNumber(rd *Reader, init rune) (parens.Any, error) {
 func readSymbol(rd *Reader, init rune) (parens.Symbol, error) {
 	beginPos := rd.Position()
 
-	s, err := rd.Token(init)
+	b, err := rd.token(init)
 	if err != nil {
-		return "", rd.annotateErr(err, beginPos, s)
+		return "", rd.annotateErr(err, beginPos, string(b))
 	}
 
-	return parens.Symbol(s), nil
+	return parens.Symbol(rd.name(b)), nil
 }
 
 func readString(rd *Reader, init rune) (parens.Any, error) {
@@ -156,30 +219,602 @@ func readString(rd *Reader, init rune) (parens.Any, error) {
 		}
 
 		if r == '\\' {
-			r2, err := rd.NextRune()
-			if err != nil {
+			if err := readEscape(rd, &b); err != nil {
 				if errors.Is(err, io.EOF) {
-					err = ErrEOF
+					return nil, rd.annotateErr(ErrEOF, beginPos, string(init)+b.String())
 				}
+				return nil, err
+			}
+			continue
+		} else if r == '"' {
+			break
+		}
+
+		b.WriteRune(r)
+	}
+
+	return parens.String(b.String()), nil
+}
+
+// readEscape reads the escape sequence following a '\' in a string literal
+// and writes the escaped value to b. Supported escapes are the single rune
+// escapes (e.g., '\n'), '\uXXXX' (with UTF-16 surrogate pairs), '\UXXXXXXXX'
+// and the byte escapes '\xHH' and '\NNN' (octal, up to '\377'). Like in Go,
+// byte escapes write a single byte and hence can construct strings that are
+// not valid UTF-8.
+func readEscape(rd *Reader, b *strings.Builder) error {
+	esc := escape{rd: rd, begin: rd.Position(), seq: []rune{'\\'}}
+
+	r, err := esc.next()
+	if err != nil {
+		return err
+	}
 
-				return nil, rd.annotateErr(err, beginPos, string(init)+b.String())
+	switch {
+	case r == 'x':
+		v, err := esc.digits(16, 2, 2, 0)
+		if err != nil {
+			return err
+		}
+		b.WriteByte(byte(v))
+
+	case r >= '0' && r <= '7':
+		v, err := esc.digits(8, 0, 2, int64(r-'0'))
+		if err != nil {
+			return err
+		} else if v > 0377 {
+			return esc.fail("octal escape value > 255")
+		}
+		b.WriteByte(byte(v))
+
+	case r == 'u':
+		v, err := esc.digits(16, 4, 4, 0)
+		if err != nil {
+			return err
+		}
+
+		r = rune(v)
+		if utf16.IsSurrogate(r) {
+			// high surrogate must be followed by the low surrogate escape.
+			if r >= 0xDC00 {
+				return esc.fail("unpaired surrogate")
 			}
 
-			// TODO: Support for Unicode escape \uNN format.
+			if err := esc.expect('\\', 'u'); err != nil {
+				return err
+			}
 
-			escaped, err := getEscape(r2)
+			low, err := esc.digits(16, 4, 4, 0)
 			if err != nil {
-				return nil, err
+				return err
 			}
-			r = escaped
-		} else if r == '"' {
+
+			if r = utf16.DecodeRune(r, rune(low)); r == unicode.ReplacementChar {
+				return esc.fail("unpaired surrogate")
+			}
+		}
+		b.WriteRune(r)
+
+	case r == 'U':
+		v, err := esc.digits(16, 8, 8, 0)
+		if err != nil {
+			return err
+		} else if v > unicode.MaxRune || utf16.IsSurrogate(rune(v)) {
+			return esc.fail("invalid code point")
+		}
+		b.WriteRune(rune(v))
+
+	default:
+		escaped, err := getEscape(r)
+		if err != nil {
+			return esc.fail("")
+		}
+		b.WriteRune(escaped)
+	}
+
+	return nil
+}
+
+// escape tracks an escape sequence being read to report malformed escapes
+// with their exact position.
+type escape struct {
+	rd    *Reader
+	begin Position
+	seq   []rune
+}
+
+func (esc *escape) next() (rune, error) {
+	r, err := esc.rd.NextRune()
+	if err != nil {
+		return -1, err
+	}
+	esc.seq = append(esc.seq, r)
+	return r, nil
+}
+
+// expect reads the runes if the input continues with them. Otherwise, the
+// runes read are returned to the input and the escape fails as an unpaired
+// surrogate.
+func (esc *escape) expect(runes ...rune) error {
+	var read []rune
+	for _, want := range runes {
+		r, err := esc.rd.NextRune()
+		if err != nil {
+			return err
+		} else if r != want {
+			read = append(read, r)
+			for i := len(read) - 1; i >= 0; i-- {
+				esc.rd.Unread(read[i]) // one at a time to keep the position.
+			}
+			return esc.fail("unpaired surrogate")
+		}
+		read = append(read, r)
+	}
+
+	esc.seq = append(esc.seq, read...)
+	return nil
+}
+
+// digits reads at least min and at most max digits in the base and returns
+// the value accumulated into v.
+func (esc *escape) digits(base, min, max int, v int64) (int64, error) {
+	for i := 0; i < max; i++ {
+		r, err := esc.rd.NextRune()
+		if err != nil {
+			if i >= min && errors.Is(err, io.EOF) {
+				break
+			}
+			return 0, err
+		}
+
+		d := digitVal(r)
+		if d >= base {
+			if i < min {
+				esc.seq = append(esc.seq, r)
+				return 0, esc.fail("")
+			}
+			esc.rd.Unread(r)
+			break
+		}
+
+		esc.seq = append(esc.seq, r)
+		v = v*int64(base) + int64(d)
+	}
+	return v, nil
+}
+
+func (esc *escape) fail(reason string) error {
+	cause := fmt.Errorf("%w '%s'", ErrInvalidEscape, string(esc.seq))
+	if reason != "" {
+		cause = fmt.Errorf("%w '%s': %s", ErrInvalidEscape, string(esc.seq), reason)
+	}
+
+	return Error{
+		Form:  string(esc.seq),
+		Cause: cause,
+		Begin: esc.begin,
+		End:   esc.rd.Position(),
+	}
+}
+
+func digitVal(r rune) int {
+	switch {
+	case r >= '0' && r <= '9':
+		return int(r - '0')
+	case r >= 'a' && r <= 'f':
+		return int(r - 'a' + 10)
+	case r >= 'A' && r <= 'F':
+		return int(r - 'A' + 10)
+	}
+	return 16
+}
+
+// readSymbolicValue reads the symbolic float values `##Inf`, `##-Inf` and
+// `##NaN`.
+func readSymbolicValue(rd *Reader, init rune) (parens.Any, error) {
+	beginPos := rd.Position()
+
+	token, err := rd.Token(-1)
+	if err != nil {
+		return nil, rd.annotateErr(err, beginPos, "##"+token)
+	}
+
+	switch token {
+	case "Inf":
+		return parens.Float64(math.Inf(1)), nil
+	case "-Inf":
+		return parens.Float64(math.Inf(-1)), nil
+	case "NaN":
+		return parens.Float64(math.NaN()), nil
+	}
+
+	err = fmt.Errorf("invalid symbolic value: '##%s'", token)
+	return nil, rd.annotateErr(err, beginPos, "##"+token)
+}
+
+// readDiscard reads and discards the next form (`#_form`).
+func readDiscard(rd *Reader, _ rune) (parens.Any, error) {
+	beginPos := rd.Position()
+
+	if _, err := rd.One(); err != nil {
+		if err == io.EOF {
+			err = ErrEOF
+		}
+		return nil, rd.annotateErr(err, beginPos, "#_")
+	}
+	return nil, ErrSkip
+}
+
+// readFnLiteral reads the anonymous function literal `#(body)` as the form
+// `(fn (%1 ... %n & %&) (body))`. `%` is same as `%1`. Number of params is
+// the highest numbered param referred in the body. Literals cannot be nested.
+func readFnLiteral(rd *Reader, init rune) (parens.Any, error) {
+	beginPos := rd.Position()
+
+	if rd.inFnLiteral {
+		err := errors.New("nested #()s are not allowed")
+		return nil, rd.annotateErr(err, beginPos, "#(")
+	}
+
+	rd.inFnLiteral = true
+	body, err := readList(rd, init)
+	rd.inFnLiteral = false
+	if err != nil {
+		return nil, err
+	}
+
+	var fa fnArgs
+	body, err = fa.replace(body)
+	if err != nil {
+		return nil, rd.annotateErr(err, beginPos, "#(")
+	}
+
+	params := make([]parens.Any, 0, fa.max+2)
+	for i := 1; i <= fa.max; i++ {
+		params = append(params, parens.Symbol("%"+strconv.Itoa(i)))
+	}
+	if fa.rest {
+		params = append(params, parens.Symbol("&"), parens.Symbol("%&"))
+	}
+
+	fn := parens.NewList(parens.Symbol("fn"), parens.NewList(params...), body)
+	if ll, ok := fn.(*parens.LinkedList); ok && rd.positions {
+		return ll.WithPos(beginPos), nil
+	}
+	return fn, nil
+}
+
+// fnArgs collects the params referred in the body of a fn literal.
+type fnArgs struct {
+	max  int
+	rest bool
+}
+
+// replace replaces `%` with `%1` in the form and records the params.
+func (fa *fnArgs) replace(form parens.Any) (parens.Any, error) {
+	switch f := form.(type) {
+	case parens.Symbol:
+		name := string(f)
+		switch {
+		case name == "%":
+			if fa.max < 1 {
+				fa.max = 1
+			}
+			return parens.Symbol("%1"), nil
+
+		case name == "%&":
+			fa.rest = true
+
+		case strings.HasPrefix(name, "%"):
+			n, err := strconv.Atoi(name[1:])
+			if err != nil || n < 1 {
+				return nil, fmt.Errorf("invalid fn literal param: '%s'", name)
+			}
+			if n > fa.max {
+				fa.max = n
+			}
+		}
+
+	case *parens.LinkedList:
+		if f == nil {
+			return f, nil
+		}
+
+		var items []parens.Any
+		err := parens.ForEach(f, func(item parens.Any) (bool, error) {
+			item, err := fa.replace(item)
+			items = append(items, item)
+			return false, err
+		})
+		if err != nil {
+			return nil, err
+		}
+
+		list := parens.NewList(items...)
+		if ll, ok := list.(*parens.LinkedList); ok && !f.Pos().IsZero() {
+			return ll.WithPos(f.Pos()), nil
+		}
+		return list, nil
+	}
+
+	return form, nil
+}
+
+// readRegex reads the regex literal `#"pattern"`. Unlike string literals,
+// backslashes are retained in the pattern as is except that `\"` can be used
+// to include a quote.
+func readRegex(rd *Reader, init rune) (parens.Any, error) {
+	beginPos := rd.Position()
+
+	var b strings.Builder
+	for {
+		r, err := rd.NextRune()
+		if err != nil {
+			if errors.Is(err, io.EOF) {
+				err = ErrEOF
+			}
+			return nil, rd.annotateErr(err, beginPos, "#\""+b.String())
+		}
+
+		if r == '"' {
 			break
 		}
 
 		b.WriteRune(r)
+		if r == '\\' {
+			r2, err := rd.NextRune()
+			if err != nil {
+				if errors.Is(err, io.EOF) {
+					err = ErrEOF
+				}
+				return nil, rd.annotateErr(err, beginPos, "#\""+b.String())
+			}
+			b.WriteRune(r2)
+		}
 	}
 
-	return parens.String(b.String()), nil
+	re, err := parens.NewRegex(b.String())
+	if err != nil {
+		return nil, rd.annotateErr(err, beginPos, "#\""+b.String()+"\"")
+	}
+	return re, nil
+}
+
+// readTagged reads the tagged literal `#tag form` using the data reader for
+// the tag.
+func readTagged(rd *Reader, init rune) (parens.Any, error) {
+	beginPos := rd.Position()
+
+	tag, err := rd.Token(init)
+	if err != nil {
+		return nil, rd.annotateErr(err, beginPos, "#"+tag)
+	}
+
+	form, err := rd.One()
+	if err != nil {
+		if err == io.EOF {
+			err = ErrEOF
+		}
+		return nil, rd.annotateErr(err, beginPos, "#"+tag)
+	}
+
+	dr, found := rd.dataReaders[tag]
+	if !found || rd.suppressed {
+		if rd.keepTags || rd.suppressed {
+			return parens.TaggedLiteral{Tag: parens.Symbol(tag), Form: form}, nil
+		}
+		return nil, rd.annotateErr(fmt.Errorf("%w: '#%s'", ErrUnknownTag, tag), beginPos, "#"+tag)
+	}
+
+	v, err := dr(form)
+	if err != nil {
+		return nil, rd.annotateErr(err, beginPos, "#"+tag)
+	}
+	return v, nil
+}
+
+// readInst reads the RFC3339 timestamp string of `#inst` literals.
+func readInst(form parens.Any) (parens.Any, error) {
+	s, ok := form.(parens.String)
+	if !ok {
+		return nil, fmt.Errorf("#inst: expecting string, not '%v'", form)
+	}
+
+	t, err := time.Parse(time.RFC3339Nano, string(s))
+	if err != nil {
+		return nil, fmt.Errorf("#inst: %w", err)
+	}
+	return parens.Inst{Time: t}, nil
+}
+
+// readUUID reads the canonical string form of `#uuid` literals.
+func readUUID(form parens.Any) (parens.Any, error) {
+	s, ok := form.(parens.String)
+	if !ok {
+		return nil, fmt.Errorf("#uuid: expecting string, not '%v'", form)
+	}
+	return parens.ParseUUID(string(s))
+}
+
+// readConditional reads the reader conditional `#?(:feature form ...)` as the
+// form of the first feature enabled (See WithFeatures()). The splicing form
+// `#?@(:feature (forms...))` splices the forms of the selected list into the
+// enclosing container. The conditional is skipped if no feature matches.
+func readConditional(rd *Reader, _ rune) (parens.Any, error) {
+	beginPos := rd.Position()
+
+	r, err := rd.NextRune()
+	if err != nil {
+		if err == io.EOF {
+			err = ErrEOF
+		}
+		return nil, rd.annotateErr(err, beginPos, "#?")
+	}
+
+	splicing := r == '@'
+	if splicing {
+		if r, err = rd.NextRune(); err != nil {
+			if err == io.EOF {
+				err = ErrEOF
+			}
+			return nil, rd.annotateErr(err, beginPos, "#?@")
+		}
+	}
+
+	if r != '(' {
+		err := errors.New("reader conditional body must be a list")
+		return nil, rd.annotateErr(err, beginPos, "#?")
+	}
+
+	// forms of the branches not selected are read without evaluating the
+	// tagged literals.
+	prevSuppressed := rd.suppressed
+	defer func() { rd.suppressed = prevSuppressed }()
+
+	var selected parens.Any
+	found, isKey := false, true
+	err = rd.Container(')', "reader conditional", func(form parens.Any) error {
+		defer func() { isKey = !isKey }()
+
+		if !isKey {
+			if !rd.suppressed {
+				selected, found = form, true
+			}
+			rd.suppressed = true
+			return nil
+		}
+
+		kw, ok := form.(parens.Keyword)
+		if !ok {
+			return fmt.Errorf("reader conditional feature must be keyword, not '%v'", form)
+		}
+		rd.suppressed = prevSuppressed || found || (kw != "default" && !rd.features[string(kw)])
+		return nil
+	})
+	if err != nil {
+		return nil, rd.annotateErr(err, beginPos, "#?")
+	} else if !isKey {
+		err := errors.New("reader conditional requires even number of forms")
+		return nil, rd.annotateErr(err, beginPos, "#?")
+	}
+
+	if !found {
+		return nil, ErrSkip
+	} else if !splicing {
+		return selected, nil
+	}
+
+	var forms splice
+	seq, ok := selected.(parens.Seq)
+	if !ok {
+		err := fmt.Errorf("splicing reader conditional requires list, not '%v'", selected)
+		return nil, rd.annotateErr(err, beginPos, "#?@")
+	}
+	err = parens.ForEach(seq, func(item parens.Any) (bool, error) {
+		forms = append(forms, item)
+		return false, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return forms, nil
+}
+
+// splice holds the forms of a splicing reader conditional to be added to the
+// enclosing container.
+type splice []parens.Any
+
+func (s splice) SExpr() (string, error) {
+	return "", errors.New("splicing reader conditional cannot be printed")
+}
+
+// readMeta reads the metadata `^meta form` and returns the form with the
+// metadata added to it. `^:kw` is same as `^{:kw true}` and `^Sym` (or
+// `^"Sym"`) is same as `^{:tag Sym}`. Entries of the metadata read replace
+// the existing entries of the form.
+func readMeta(rd *Reader, _ rune) (parens.Any, error) {
+	beginPos := rd.Position()
+
+	kvs, err := readMetaEntries(rd)
+	if err != nil {
+		return nil, rd.annotateErr(err, beginPos, "^")
+	}
+
+	form, err := rd.One()
+	if err != nil {
+		if err == io.EOF {
+			err = ErrEOF
+		}
+		return nil, rd.annotateErr(err, beginPos, "^")
+	}
+
+	target, ok := form.(parens.Annotatable)
+	if !ok {
+		err := fmt.Errorf("metadata cannot be applied to '%s'", reflect.TypeOf(form))
+		return nil, rd.annotateErr(err, beginPos, "^")
+	}
+
+	meta := target.Meta()
+	if meta == nil {
+		meta, err = parens.NewHashMap()
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	for i := 0; i < len(kvs); i += 2 {
+		if meta, err = meta.Assoc(kvs[i], kvs[i+1]); err != nil {
+			return nil, rd.annotateErr(err, beginPos, "^")
+		}
+	}
+
+	return target.WithMeta(meta)
+}
+
+func readMetaEntries(rd *Reader) ([]parens.Any, error) {
+	if err := rd.SkipSpaces(); err != nil {
+		if err == io.EOF {
+			err = ErrEOF
+		}
+		return nil, err
+	}
+
+	r, err := rd.NextRune()
+	if err != nil {
+		return nil, err
+	}
+
+	if r == '{' {
+		var kvs []parens.Any
+		err := rd.Container('}', "map", func(val parens.Any) error {
+			kvs = append(kvs, val)
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		} else if len(kvs)%2 != 0 {
+			return nil, errors.New("expecting even number of forms for map")
+		}
+		return kvs, nil
+	}
+	rd.Unread(r)
+
+	form, err := rd.One()
+	if err != nil {
+		if err == io.EOF {
+			err = ErrEOF
+		}
+		return nil, err
+	}
+
+	switch f := form.(type) {
+	case parens.Keyword:
+		return []parens.Any{f, parens.Bool(true)}, nil
+
+	case parens.Symbol, parens.String:
+		return []parens.Any{parens.Keyword("tag"), f}, nil
+	}
+	return nil, fmt.Errorf("metadata must be keyword, symbol, string or map, not '%s'", reflect.TypeOf(form))
 }
 
 func readComment(rd *Reader, _ rune) (parens.Any, error) {
@@ -200,12 +835,12 @@ func readComment(rd *Reader, _ rune) (parens.Any, error) {
 func readKeyword(rd *Reader, init rune) (parens.Any, error) {
 	beginPos := rd.Position()
 
-	token, err := rd.Token(-1)
+	b, err := rd.token(-1)
 	if err != nil {
-		return nil, rd.annotateErr(err, beginPos, token)
+		return nil, rd.annotateErr(err, beginPos, string(b))
 	}
 
-	return parens.Keyword(token), nil
+	return parens.Keyword(rd.name(b)), nil
 }
 
 func readCharacter(rd *Reader, _ rune) (parens.Any, error) {
@@ -238,6 +873,26 @@ func readCharacter(rd *Reader, _ rune) (parens.Any, error) {
 	return nil, fmt.Errorf("unsupported character: '\\%s'", token)
 }
 
+func readHashMap(rd *Reader, _ rune) (parens.Any, error) {
+	const mapEnd = '}'
+
+	beginPos := rd.Position()
+
+	var forms []parens.Any
+	if err := rd.Container(mapEnd, "map", func(val parens.Any) error {
+		forms = append(forms, val)
+		return nil
+	}); err != nil {
+		return nil, rd.annotateErr(err, beginPos, "")
+	}
+
+	hm, err := parens.NewHashMap(forms...)
+	if err != nil {
+		return nil, rd.annotateErr(err, beginPos, "")
+	}
+	return hm, nil
+}
+
 func readList(rd *Reader, _ rune) (parens.Any, error) {
 	const listEnd = ')'
 
@@ -251,7 +906,11 @@ func readList(rd *Reader, _ rune) (parens.Any, error) {
 		return nil, rd.annotateErr(err, beginPos, "")
 	}
 
-	return parens.NewList(forms...), nil
+	list := parens.NewList(forms...)
+	if ll, ok := list.(*parens.LinkedList); ok && rd.positions && ll != nil {
+		return ll.WithPos(beginPos), nil
+	}
+	return list, nil
 }
 
 func quoteFormReader(expandFunc string) Macro {
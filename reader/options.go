@@ -35,6 +35,60 @@ func WithPredefinedSymbols(ss map[string]parens.Any) Option {
 	}
 }
 
+// WithPositions enables annotating the lists read with their position in
+// the source (See parens.Positional). Positions are used by the Env to
+// record source location of definitions etc.
+func WithPositions() Option {
+	return func(rd *Reader) {
+		rd.positions = true
+	}
+}
+
+// WithInterner sets the table used to intern the symbols and keywords read.
+// Symbols and keywords are not interned by default or if in is nil. See
+// parens.Interner.
+func WithInterner(in *parens.Interner) Option {
+	return func(rd *Reader) {
+		rd.interner = in
+	}
+}
+
+// WithDataReaders sets the data readers for the tagged literals. Readers are
+// added to the built-in readers for `#inst` and `#uuid` and replace them if
+// the tags are same. A nil reader removes the reader for the tag.
+func WithDataReaders(readers map[string]DataReader) Option {
+	return func(rd *Reader) {
+		for tag, dr := range readers {
+			if dr == nil {
+				delete(rd.dataReaders, tag)
+			} else {
+				rd.dataReaders[tag] = dr
+			}
+		}
+	}
+}
+
+// WithUnknownTags sets the handling of tagged literals without a data reader.
+// If preserve is true, such literals are read as parens.TaggedLiteral values.
+// Otherwise, reading them fails with ErrUnknownTag (default).
+func WithUnknownTags(preserve bool) Option {
+	return func(rd *Reader) {
+		rd.keepTags = preserve
+	}
+}
+
+// WithFeatures sets the features (e.g., "dialect-a") used to select the
+// branches of reader conditionals (`#?(:dialect-a x :default y)`). The
+// `:default` branch is selected if none of the features match.
+func WithFeatures(features ...string) Option {
+	return func(rd *Reader) {
+		rd.features = map[string]bool{}
+		for _, f := range features {
+			rd.features[f] = true
+		}
+	}
+}
+
 func withDefaults(opt []Option) []Option {
 	return append([]Option{
 		WithNumReader(nil),
@@ -9,13 +9,15 @@ import (
 	"errors"
 	"fmt"
 	"io"
-	"math"
+	"math/big"
 	"net"
 	"os"
 	"reflect"
 	"strconv"
 	"strings"
 	"unicode"
+	"unicode/utf16"
+	"unicode/utf8"
 
 	"github.com/spy16/parens"
 )
@@ -29,7 +31,7 @@ var (
 		'\\': '\\',
 		't':  '\t',
 		'a':  '\a',
-		'f':  '\a',
+		'f':  '\f',
 		'r':  '\r',
 		'b':  '\b',
 		'v':  '\v',
@@ -60,11 +62,24 @@ func New(r io.Reader, opts ...Option) *Reader {
 			'\\': readCharacter,
 			'(':  readList,
 			')':  UnmatchedDelimiter(),
+			'{':  readHashMap,
+			'}':  UnmatchedDelimiter(),
 			'\'': quoteFormReader("quote"),
 			'~':  quoteFormReader("unquote"),
 			'`':  quoteFormReader("syntax-quote"),
+			'^':  readMeta,
+		},
+		dispatch: map[rune]Macro{
+			'#': readSymbolicValue,
+			'_': readDiscard,
+			'(': readFnLiteral,
+			'"': readRegex,
+			'?': readConditional,
+		},
+		dataReaders: map[string]DataReader{
+			"inst": readInst,
+			"uuid": readUUID,
 		},
-		dispatch:  map[rune]Macro{},
 		numReader: readNumber,
 	}
 
@@ -88,8 +103,18 @@ type Reader struct {
 	macros      map[rune]Macro
 	dispatch    map[rune]Macro
 	dispatching bool
+	inFnLiteral bool
 	predef      map[string]parens.Any
 	numReader   Macro
+	positions   bool
+	dataReaders map[string]DataReader
+	keepTags    bool
+	features    map[string]bool
+	suppressed  bool // tagged literals are not being evaluated.
+	interner    *parens.Interner
+	scratch     []byte
+	recording   bool       // consumed runes are recorded to the tape.
+	tape        []tapeRune // runes of the form being read. See AllRecovering().
 }
 
 // All consumes characters from stream until EOF and returns a list of all the forms
@@ -117,15 +142,27 @@ func (rd *Reader) All() ([]parens.Any, error) {
 // obtained using Position().
 func (rd *Reader) One() (parens.Any, error) {
 	for {
-		form, err := rd.readOne()
-		if err != nil {
-			if errors.Is(err, ErrSkip) {
-				continue
-			}
-			return nil, err
+		form, err := rd.topLevel()
+		if errors.Is(err, ErrSkip) {
+			continue
+		}
+		return form, err
+	}
+}
+
+// topLevel reads the next top-level form. Returns ErrSkip if the form read
+// is a no-op form.
+func (rd *Reader) topLevel() (parens.Any, error) {
+	form, err := rd.readOne()
+	if err != nil {
+		return nil, err
+	} else if _, isSplice := form.(splice); isSplice {
+		return nil, Error{
+			Form:  "#?@",
+			Cause: errors.New("splicing reader conditional not allowed here"),
 		}
-		return form, nil
 	}
+	return form, nil
 }
 
 // IsTerminal returns true if the rune should terminate a form. Macro trigger runes
@@ -181,6 +218,10 @@ func (rd *Reader) NextRune() (rune, error) {
 		r = temp
 	}
 
+	if rd.recording {
+		rd.tape = append(rd.tape, tapeRune{r: r, line: rd.line, col: rd.col, lastCol: rd.lastCol})
+	}
+
 	if r == '\n' {
 		rd.line++
 		rd.lastCol = rd.col
@@ -210,12 +251,20 @@ func (rd *Reader) Unread(runes ...rune) {
 		rd.col--
 	}
 
+	if rd.recording {
+		n := len(rd.tape) - len(runes)
+		if n < 0 {
+			n = 0
+		}
+		rd.tape = rd.tape[:n]
+	}
+
 	rd.buf = append(runes, rd.buf...)
 }
 
 // Position returns information about the stream including file name and the position
 // of the reader.
-func (rd Reader) Position() Position {
+func (rd *Reader) Position() Position {
 	file := strings.TrimSpace(rd.File)
 	return Position{
 		File: file,
@@ -246,9 +295,18 @@ func (rd *Reader) SkipSpaces() error {
 // Token reads one token from the reader and returns. If init is not -1, it is included
 // as first character in the token.
 func (rd *Reader) Token(init rune) (string, error) {
-	var b strings.Builder
+	b, err := rd.token(init)
+	return string(b), err
+}
+
+// token is same as Token but reads into the scratch buffer of the reader.
+// Returned slice is valid only until the next call.
+func (rd *Reader) token(init rune) ([]byte, error) {
+	var enc [utf8.UTFMax]byte
+
+	b := rd.scratch[:0]
 	if init != -1 {
-		b.WriteRune(init)
+		b = append(b, enc[:utf8.EncodeRune(enc[:], init)]...)
 	}
 
 	for {
@@ -257,7 +315,8 @@ func (rd *Reader) Token(init rune) (string, error) {
 			if err == io.EOF {
 				break
 			}
-			return b.String(), err
+			rd.scratch = b
+			return b, err
 		}
 
 		if rd.IsTerminal(r) {
@@ -265,15 +324,25 @@ func (rd *Reader) Token(init rune) (string, error) {
 			break
 		}
 
-		b.WriteRune(r)
+		b = append(b, enc[:utf8.EncodeRune(enc[:], r)]...)
 	}
 
-	return b.String(), nil
+	rd.scratch = b
+	return b, nil
+}
+
+// name returns the name of a symbol or keyword read into b, interned if the
+// reader has an interner.
+func (rd *Reader) name(b []byte) string {
+	if rd.interner == nil {
+		return string(b)
+	}
+	return rd.interner.Intern(b)
 }
 
 // Container reads multiple forms until 'end' rune is reached. Should be used to read
 // collection types like List etc. formType is only used to annotate errors.
-func (rd Reader) Container(end rune, formType string, f func(parens.Any) error) error {
+func (rd *Reader) Container(end rune, formType string, f func(parens.Any) error) error {
 	for {
 		if err := rd.SkipSpaces(); err != nil {
 			if err == io.EOF {
@@ -303,6 +372,15 @@ func (rd Reader) Container(end rune, formType string, f func(parens.Any) error)
 			return err
 		}
 
+		if forms, isSplice := expr.(splice); isSplice {
+			for _, form := range forms {
+				if err = f(form); err != nil {
+					return err
+				}
+			}
+			continue
+		}
+
 		// TODO(performance):  verify `f` is inlined by the compiler
 		if err = f(expr); err != nil {
 			return err
@@ -314,6 +392,12 @@ func (rd Reader) Container(end rune, formType string, f func(parens.Any) error)
 
 // readOne is same as One() but always returns un-annotated errors.
 func (rd *Reader) readOne() (parens.Any, error) {
+	if rd.dispatching {
+		// forms nested in a dispatch form are read with the usual terminals.
+		rd.dispatching = false
+		defer func() { rd.dispatching = true }()
+	}
+
 	if err := rd.SkipSpaces(); err != nil {
 		return nil, err
 	}
@@ -372,6 +456,9 @@ func (rd *Reader) execDispatch() (parens.Any, error) {
 
 	dispatchMacro, found := rd.dispatch[r2]
 	if !found {
+		if unicode.IsLetter(r2) {
+			return readTagged(rd, r2)
+		}
 		rd.Unread(r2)
 		return nil, nil
 	}
@@ -396,6 +483,10 @@ func (rd *Reader) annotateErr(err error, beginPos Position, form string) error {
 
 	readErr := Error{}
 	if e, ok := err.(Error); ok {
+		if e.Begin != (Position{}) {
+			// already annotated with the precise position by a nested form.
+			return e
+		}
 		readErr = e
 	} else {
 		readErr = Error{Cause: err}
@@ -413,22 +504,24 @@ func readUnicodeChar(token string, base int) (parens.Char, error) {
 		return -1, fmt.Errorf("invalid unicode character: '\\%s'", token)
 	}
 
-	if num < 0 || num >= unicode.MaxRune {
+	if num < 0 || num > unicode.MaxRune {
 		return -1, fmt.Errorf("invalid unicode character: '\\%s'", token)
+	} else if utf16.IsSurrogate(rune(num)) {
+		return -1, fmt.Errorf("invalid unicode character: '\\%s': surrogate", token)
 	}
 
 	return parens.Char(num), nil
 }
 
-func parseRadix(numStr string) (parens.Int64, error) {
+func parseRadix(numStr string) (parens.Any, error) {
 	parts := strings.Split(numStr, "r")
 	if len(parts) != 2 {
-		return 0, fmt.Errorf("%w (radix notation): '%s'", ErrNumberFormat, numStr)
+		return nil, fmt.Errorf("%w (radix notation): '%s'", ErrNumberFormat, numStr)
 	}
 
 	base, err := strconv.ParseInt(parts[0], 10, 64)
 	if err != nil {
-		return 0, fmt.Errorf("%w (radix notation): '%s'", ErrNumberFormat, numStr)
+		return nil, fmt.Errorf("%w (radix notation): '%s'", ErrNumberFormat, numStr)
 	}
 
 	repr := parts[1]
@@ -437,12 +530,64 @@ func parseRadix(numStr string) (parens.Int64, error) {
 		repr = "-" + repr
 	}
 
-	v, err := strconv.ParseInt(repr, int(base), 64)
-	if err != nil {
-		return 0, fmt.Errorf("%w (radix notation): '%s'", ErrNumberFormat, numStr)
+	if base < 2 || base > 36 || strings.HasPrefix(parts[1], "-") || strings.HasPrefix(parts[1], "+") {
+		return nil, fmt.Errorf("%w (radix notation): '%s'", ErrNumberFormat, numStr)
+	}
+
+	v, ok := new(big.Int).SetString(repr, int(base))
+	if !ok {
+		return nil, fmt.Errorf("%w (radix notation): '%s'", ErrNumberFormat, numStr)
+	} else if v.IsInt64() {
+		return parens.Int64(v.Int64()), nil
+	}
+	return parens.BigInt{Int: v}, nil
+}
+
+// parseRatio parses a ratio `n/d` of decimal integers. Ratios that are
+// integers are returned as integers (e.g., `4/2` is 2).
+func parseRatio(numStr string) (parens.Any, error) {
+	parts := strings.Split(numStr, "/")
+	if len(parts) != 2 || strings.ContainsAny(parts[1], "+-") {
+		return nil, fmt.Errorf("%w (ratio): '%s'", ErrNumberFormat, numStr)
 	}
 
-	return parens.Int64(v), nil
+	num, ok := new(big.Int).SetString(parts[0], 10)
+	if !ok {
+		return nil, fmt.Errorf("%w (ratio): '%s'", ErrNumberFormat, numStr)
+	}
+
+	den, ok := new(big.Int).SetString(parts[1], 10)
+	if !ok || den.Sign() == 0 {
+		return nil, fmt.Errorf("%w (ratio): '%s'", ErrNumberFormat, numStr)
+	}
+	return parens.NewRatio(num, den)
+}
+
+// stripSeparators removes the `_` digit separators from the number. Each
+// separator must be between two digits.
+func stripSeparators(numStr string, isDigit func(b byte) bool) (string, error) {
+	b := make([]byte, 0, len(numStr))
+	for i := 0; i < len(numStr); i++ {
+		if numStr[i] != '_' {
+			b = append(b, numStr[i])
+			continue
+		}
+
+		if i == 0 || i == len(numStr)-1 || !isDigit(numStr[i-1]) || !isDigit(numStr[i+1]) {
+			return numStr, fmt.Errorf("%w (misplaced '_'): '%s'", ErrNumberFormat, numStr)
+		}
+	}
+	return string(b), nil
+}
+
+func isDecimalDigit(b byte) bool { return '0' <= b && b <= '9' }
+
+func isHexDigit(b byte) bool {
+	return isDecimalDigit(b) || ('a' <= b && b <= 'f') || ('A' <= b && b <= 'F')
+}
+
+func isAlphaNum(b byte) bool {
+	return isDecimalDigit(b) || ('a' <= b && b <= 'z') || ('A' <= b && b <= 'Z')
 }
 
 func parseScientific(numStr string) (parens.Float64, error) {
@@ -451,23 +596,25 @@ func parseScientific(numStr string) (parens.Float64, error) {
 		return 0, fmt.Errorf("%w (scientific notation): '%s'", ErrNumberFormat, numStr)
 	}
 
-	base, err := strconv.ParseFloat(parts[0], 64)
-	if err != nil {
+	if _, err := strconv.ParseFloat(parts[0], 64); err != nil {
+		return 0, fmt.Errorf("%w (scientific notation): '%s'", ErrNumberFormat, numStr)
+	} else if _, err := strconv.ParseInt(parts[1], 10, 64); err != nil {
 		return 0, fmt.Errorf("%w (scientific notation): '%s'", ErrNumberFormat, numStr)
 	}
 
-	pow, err := strconv.ParseInt(parts[1], 10, 64)
-	if err != nil {
+	// parse as a whole to get the closest float. out of range values are
+	// read as infinity.
+	v, err := strconv.ParseFloat(numStr, 64)
+	if err != nil && !errors.Is(err, strconv.ErrRange) {
 		return 0, fmt.Errorf("%w (scientific notation): '%s'", ErrNumberFormat, numStr)
 	}
-
-	return parens.Float64(base * math.Pow(10, float64(pow))), nil
+	return parens.Float64(v), nil
 }
 
 func getEscape(r rune) (rune, error) {
 	escaped, found := escapeMap[r]
 	if !found {
-		return -1, fmt.Errorf("illegal escape sequence '\\%c'", r)
+		return -1, fmt.Errorf("%w '\\%c'", ErrInvalidEscape, r)
 	}
 
 	return escaped, nil
@@ -498,15 +645,4 @@ func inferFileName(rs io.Reader) string {
 
 // Position represents the positional information about a value read
 // by reader.
-type Position struct {
-	File string
-	Ln   int
-	Col  int
-}
-
-func (p Position) String() string {
-	if p.File == "" {
-		p.File = "<unknown>"
-	}
-	return fmt.Sprintf("%s:%d:%d", p.File, p.Ln, p.Col)
-}
+type Position = parens.Position
@@ -2,11 +2,20 @@ package reader
 
 import (
 	"bytes"
+	"errors"
+	"fmt"
 	"io"
+	"math"
+	"math/big"
+	"math/rand"
 	"os"
 	"reflect"
 	"strings"
 	"testing"
+	"time"
+	"unicode"
+	"unicode/utf16"
+	"unsafe"
 
 	"github.com/spy16/parens"
 )
@@ -375,9 +384,106 @@ func TestReader_One_Number(t *testing.T) {
 			src:     "9.3.2",
 			wantErr: true,
 		},
+		{
+			name: "HexWithE",
+			src:  "0x1e",
+			want: parens.Int64(0x1e),
+		},
+		{
+			name: "Separators",
+			src:  "-1_000_000",
+			want: parens.Int64(-1000000),
+		},
+		{
+			name: "HexSeparators",
+			src:  "0xff_ff",
+			want: parens.Int64(0xffff),
+		},
+		{
+			name: "RadixSeparators",
+			src:  "36rzz_zz",
+			want: parens.Int64(36*36*36*36 - 1),
+		},
+		{
+			name: "FloatSeparators",
+			src:  "1_000.000_5e1_0",
+			want: parens.Float64(1000.0005e10),
+		},
+		{
+			name:    "LeadingSeparator",
+			src:     "0x_ff",
+			wantErr: true,
+		},
+		{
+			name:    "TrailingSeparator",
+			src:     "1_",
+			wantErr: true,
+		},
+		{
+			name:    "DoubleSeparator",
+			src:     "1__0",
+			wantErr: true,
+		},
+		{
+			name:    "SeparatorBeforePoint",
+			src:     "1_.5",
+			wantErr: true,
+		},
 	})
 }
 
+func TestReader_One_BigNumber(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		src     string
+		want    string
+		wantErr bool
+	}{
+		{src: "12N", want: "12N"},
+		{src: "-0x1fN", want: "-31N"},
+		{src: "1_000N", want: "1000N"},
+		{src: "9223372036854775807", want: "9223372036854775807"},
+		{src: "9223372036854775808", want: "9223372036854775808N"},
+		{src: "-9_223_372_036_854_775_809", want: "-9223372036854775809N"},
+		{src: "16r1_0000_0000_0000_0000", want: "18446744073709551616N"},
+		{src: "1/3", want: "1/3"},
+		{src: "-2/4", want: "-1/2"},
+		{src: "4/2", want: "2"},
+		{src: "36893488147419103232/2", want: "18446744073709551616N"},
+		{src: "0/5", want: "0"},
+		{src: "1.50M", want: "1.50M"},
+		{src: "-0.005M", want: "-0.005M"},
+		{src: "12M", want: "12M"},
+		{src: "1.5e3M", want: "1500M"},
+		{src: "1_000.25e-2M", want: "10.0025M"},
+		{src: "1.5N", wantErr: true},
+		{src: "1/0", wantErr: true},
+		{src: "1/-2", wantErr: true},
+		{src: "1.5/2", wantErr: true},
+		{src: "2r1/2", wantErr: true},
+		{src: "0x1M", wantErr: true},
+		{src: "99r1", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		got, err := New(strings.NewReader(tt.src)).One()
+		if (err != nil) != tt.wantErr {
+			t.Errorf("%s: error = %v, wantErr %v", tt.src, err, tt.wantErr)
+			continue
+		} else if tt.wantErr {
+			if !errors.Is(err, ErrNumberFormat) {
+				t.Errorf("%s: expecting ErrNumberFormat, got %v", tt.src, err)
+			}
+			continue
+		}
+
+		if s, _ := got.SExpr(); s != tt.want {
+			t.Errorf("%s: got %s (%T), want %s", tt.src, s, got, tt.want)
+		}
+	}
+}
+
 func TestReader_One_String(t *testing.T) {
 	executeReaderTests(t, []readerTestCase{
 		{
@@ -415,9 +521,114 @@ func TestReader_One_String(t *testing.T) {
 			src:     `"hello\`,
 			wantErr: true,
 		},
+		{
+			name: "EscapeFormFeed",
+			src:  `"a\fb"`,
+			want: parens.String("a\fb"),
+		},
+		{
+			name: "EscapeUnicode",
+			src:  `"\u00e9t\u00C9"`,
+			want: parens.String("étÉ"),
+		},
+		{
+			name: "EscapeSurrogatePair",
+			src:  `"\ud83d\ude00"`,
+			want: parens.String("😀"),
+		},
+		{
+			name: "EscapeLongUnicode",
+			src:  `"\U0001F600"`,
+			want: parens.String("😀"),
+		},
+		{
+			name: "EscapeHexAndOctal",
+			src:  `"\x41\101\0\12x\xff"`,
+			want: parens.String("AA\x00\nx\xff"),
+		},
+		{
+			name:    "UnpairedSurrogate",
+			src:     `"\ud83dx"`,
+			wantErr: true,
+		},
+		{
+			name:    "LowSurrogate",
+			src:     `"\ude00"`,
+			wantErr: true,
+		},
+		{
+			name:    "ShortUnicode",
+			src:     `"\u12g4"`,
+			wantErr: true,
+		},
+		{
+			name:    "LargeOctal",
+			src:     `"\400"`,
+			wantErr: true,
+		},
+		{
+			name:    "CodePointOutOfRange",
+			src:     `"\U00110000"`,
+			wantErr: true,
+		},
+		{
+			name:    "UnicodeEOF",
+			src:     `"\u12`,
+			wantErr: true,
+		},
 	})
 }
 
+func TestReader_EscapeErrors(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		src        string
+		form       string
+		begin, end Position
+	}{
+		{src: "(\"ok\"\n  \"a\\u12g4\")", form: `\u12g`, begin: Position{File: "<string>", Ln: 2, Col: 5}, end: Position{File: "<string>", Ln: 2, Col: 9}},
+		{src: `"\ud83d\n"`, form: `\ud83d`, begin: Position{File: "<string>", Ln: 1, Col: 2}, end: Position{File: "<string>", Ln: 1, Col: 7}},
+		{src: `"\uD83D"`, form: `\uD83D`, begin: Position{File: "<string>", Ln: 1, Col: 2}, end: Position{File: "<string>", Ln: 1, Col: 7}},
+		{src: `"\uD83Dabc"`, form: `\uD83D`, begin: Position{File: "<string>", Ln: 1, Col: 2}, end: Position{File: "<string>", Ln: 1, Col: 7}},
+		{src: `"\q"`, form: `\q`, begin: Position{File: "<string>", Ln: 1, Col: 2}, end: Position{File: "<string>", Ln: 1, Col: 3}},
+	}
+
+	for _, tt := range tests {
+		_, err := New(strings.NewReader(tt.src)).One()
+		if !errors.Is(err, ErrInvalidEscape) {
+			t.Errorf("%s: expecting ErrInvalidEscape, got %v", tt.src, err)
+			continue
+		}
+
+		e := err.(Error)
+		if e.Form != tt.form || e.Begin != tt.begin || e.End != tt.end {
+			t.Errorf("%s: got form=%s begin=%v end=%v, want form=%s begin=%v end=%v",
+				tt.src, e.Form, e.Begin, e.End, tt.form, tt.begin, tt.end)
+		}
+	}
+
+	for _, src := range []string{`"\u12`, `"\uD83D`, `"\uD83D\`} {
+		_, err := New(strings.NewReader(src)).One()
+		if !errors.Is(err, ErrEOF) {
+			t.Errorf("%s: expecting ErrEOF for incomplete escape, got %v", src, err)
+		}
+	}
+}
+
+func TestReader_StringRoundTrip(t *testing.T) {
+	t.Parallel()
+
+	for _, s := range []string{"plain", "tab\there\n", "bell\a\x00\x7f", "\u2028 and 😀", "bad \xff\xfe utf8", "\U0010FFFF"} {
+		got, err := New(strings.NewReader(parens.String(s).String())).One()
+		if err != nil {
+			t.Errorf("%q: unexpected error: %v", s, err)
+		} else if got != parens.String(s) {
+			t.Errorf("%q: read back as %q", s, got)
+		}
+	}
+}
+
 func TestReader_One_Keyword(t *testing.T) {
 	executeReaderTests(t, []readerTestCase{
 		{
@@ -490,6 +701,11 @@ func TestReader_One_Character(t *testing.T) {
 			src:     `\u-100`,
 			wantErr: true,
 		},
+		{
+			name:    "LoneSurrogate",
+			src:     `\uD800`,
+			wantErr: true,
+		},
 		{
 			name:    "UnknownSpecial",
 			src:     `\hello`,
@@ -608,3 +824,984 @@ func executeReaderTests(t *testing.T, tests []readerTestCase) {
 		})
 	}
 }
+
+func TestReader_WithPositions(t *testing.T) {
+	rd := New(strings.NewReader("\n  (a (b))"), WithPositions())
+	rd.File = "test.lisp"
+
+	form, err := rd.One()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	want := Position{File: "test.lisp", Ln: 2, Col: 3}
+	if got := form.(parens.Positional).Pos(); got != want {
+		t.Errorf("Pos() got = %v, want = %v", got, want)
+	}
+}
+
+func TestReader_WithPositions_AfterNested(t *testing.T) {
+	rd := New(strings.NewReader("(a\n  (b))\n(c)\n{:d (e\n)}\n(f)"), WithPositions())
+	rd.File = "test.lisp"
+
+	forms, err := rd.All()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	for i, ln := range map[int]int{1: 3, 3: 6} {
+		want := Position{File: "test.lisp", Ln: ln, Col: 1}
+		if got := forms[i].(parens.Positional).Pos(); got != want {
+			t.Errorf("Pos() of form %d got = %v, want = %v", i, got, want)
+		}
+	}
+}
+
+func TestReader_WithInterner(t *testing.T) {
+	in := parens.NewInterner()
+
+	forms, err := New(strings.NewReader("(foo :bar) (foo :bar)"), WithInterner(in)).All()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if in.Len() != 2 {
+		t.Errorf("Len() got = %d, want = 2", in.Len())
+	}
+
+	first, second := forms[0].(*parens.LinkedList), forms[1].(*parens.LinkedList)
+	sym1, _ := first.First()
+	sym2, _ := second.First()
+	if !sameData(string(sym1.(parens.Symbol)), string(sym2.(parens.Symbol))) {
+		t.Errorf("symbols read are not interned")
+	}
+	if !reflect.DeepEqual(forms[0], forms[1]) {
+		t.Errorf("forms got = %#v, want = %#v", forms[1], forms[0])
+	}
+
+	forms, err = New(strings.NewReader("foo foo")).All()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if sameData(string(forms[0].(parens.Symbol)), string(forms[1].(parens.Symbol))) {
+		t.Errorf("symbols must not be interned without WithInterner()")
+	}
+}
+
+func BenchmarkReader_RepeatedKeys(b *testing.B) {
+	src := strings.Repeat("(:name :value :port :enabled) ", 100)
+
+	b.Run("Plain", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			if _, err := New(strings.NewReader(src)).All(); err != nil {
+				b.Fatalf("unexpected error: %v", err)
+			}
+		}
+	})
+
+	b.Run("Interned", func(b *testing.B) {
+		in := parens.NewInterner()
+
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			if _, err := New(strings.NewReader(src), WithInterner(in)).All(); err != nil {
+				b.Fatalf("unexpected error: %v", err)
+			}
+		}
+	})
+}
+
+func sameData(a, b string) bool {
+	ha := (*reflect.StringHeader)(unsafe.Pointer(&a))
+	hb := (*reflect.StringHeader)(unsafe.Pointer(&b))
+	return ha.Data == hb.Data && ha.Len == hb.Len
+}
+
+func TestReader_One_SymbolicValues(t *testing.T) {
+	executeReaderTests(t, []readerTestCase{
+		{
+			name: "Inf",
+			src:  `##Inf`,
+			want: parens.Float64(math.Inf(1)),
+		},
+		{
+			name: "NegativeInf",
+			src:  `##-Inf`,
+			want: parens.Float64(math.Inf(-1)),
+		},
+		{
+			name:    "Unknown",
+			src:     `##Infinity`,
+			wantErr: true,
+		},
+	})
+
+	form, err := New(strings.NewReader("##NaN")).One()
+	if f, ok := form.(parens.Float64); err != nil || !ok || !math.IsNaN(float64(f)) {
+		t.Errorf("One() got = %#v (err=%v), want NaN", form, err)
+	}
+}
+
+func TestReader_One_HashMap(t *testing.T) {
+	want, _ := parens.NewHashMap(parens.Keyword("a"), parens.Int64(1), parens.String("b"), parens.NewList())
+	executeReaderTests(t, []readerTestCase{
+		{
+			name: "Empty",
+			src:  `{}`,
+			want: mustHashMap(),
+		},
+		{
+			name: "Entries",
+			src:  `{:a 1, "b" ()}`,
+			want: want,
+		},
+		{
+			name:    "OddForms",
+			src:     `{:a}`,
+			wantErr: true,
+		},
+		{
+			name:    "Unterminated",
+			src:     `{:a 1`,
+			wantErr: true,
+		},
+	})
+}
+
+func TestReader_One_Dispatch(t *testing.T) {
+	sym := func(s string) parens.Any { return parens.Symbol(s) }
+	executeReaderTests(t, []readerTestCase{
+		{
+			name: "Discard",
+			src:  `#_ a b`,
+			want: sym("b"),
+		},
+		{
+			name: "DiscardNested",
+			src:  `(a #_(b c) #_ #_ d e f)`,
+			want: parens.NewList(sym("a"), sym("f")),
+		},
+		{
+			name:    "DiscardEOF",
+			src:     `#_`,
+			wantErr: true,
+		},
+		{
+			name: "FnLiteral",
+			src:  `#(+ % %3)`,
+			want: parens.NewList(sym("fn"), parens.NewList(sym("%1"), sym("%2"), sym("%3")),
+				parens.NewList(sym("+"), sym("%1"), sym("%3"))),
+		},
+		{
+			name: "FnLiteralRest",
+			src:  `#(apply foo_bar (list %1) %&)`,
+			want: parens.NewList(sym("fn"), parens.NewList(sym("%1"), sym("&"), sym("%&")),
+				parens.NewList(sym("apply"), sym("foo_bar"), parens.NewList(sym("list"), sym("%1")), sym("%&"))),
+		},
+		{
+			name:    "FnLiteralNested",
+			src:     `#(a #(b %))`,
+			wantErr: true,
+		},
+		{
+			name:    "FnLiteralInvalidParam",
+			src:     `#(a %x)`,
+			wantErr: true,
+		},
+		{
+			name: "Regex",
+			src:  `#"\d+\"[a-z]*"`,
+			want: mustRegex(`\d+\"[a-z]*`),
+		},
+		{
+			name:    "InvalidRegex",
+			src:     `#"(a"`,
+			wantErr: true,
+		},
+		{
+			name:    "RegexEOF",
+			src:     `#"abc`,
+			wantErr: true,
+		},
+	})
+}
+
+func TestReader_One_Tagged(t *testing.T) {
+	id, _ := parens.ParseUUID("f81d4fae-7dec-11d0-a765-00a0c91e6bf6")
+	executeReaderTests(t, []readerTestCase{
+		{
+			name: "Inst",
+			src:  `#inst "2020-09-09T10:30:00.5Z"`,
+			want: parens.Inst{Time: time.Date(2020, 9, 9, 10, 30, 0, 5e8, time.UTC)},
+		},
+		{
+			name: "UUID",
+			src:  `#uuid "F81D4FAE-7DEC-11D0-A765-00A0C91E6BF6"`,
+			want: id,
+		},
+		{
+			name:    "InvalidInst",
+			src:     `#inst "2020-09-09"`,
+			wantErr: true,
+		},
+		{
+			name:    "InvalidUUID",
+			src:     `#uuid "f81d4fae7dec11d0a76500a0c91e6bf6"`,
+			wantErr: true,
+		},
+		{
+			name:    "UnknownTag",
+			src:     `#point (1 2)`,
+			wantErr: true,
+		},
+		{
+			name:    "MissingForm",
+			src:     `#inst`,
+			wantErr: true,
+		},
+	})
+
+	t.Run("DataReaders", func(t *testing.T) {
+		rd := New(strings.NewReader(`(#point (1 2) #inst "x")`), WithDataReaders(map[string]DataReader{
+			"point": func(form parens.Any) (parens.Any, error) { return parens.Keyword("point"), nil },
+			"inst":  func(form parens.Any) (parens.Any, error) { return form, nil },
+		}))
+
+		got, err := rd.One()
+		want := parens.NewList(parens.Keyword("point"), parens.String("x"))
+		if err != nil || !reflect.DeepEqual(got, want) {
+			t.Errorf("One() got = %#v (err=%v), want %#v", got, err, want)
+		}
+	})
+
+	t.Run("UnknownTag", func(t *testing.T) {
+		_, err := New(strings.NewReader(`#point (1 2)`)).One()
+		if !errors.Is(err, ErrUnknownTag) {
+			t.Errorf("expecting ErrUnknownTag, got %v", err)
+		}
+
+		got, err := New(strings.NewReader(`#point (1 2)`), WithUnknownTags(true)).One()
+		want := parens.TaggedLiteral{Tag: "point", Form: parens.NewList(parens.Int64(1), parens.Int64(2))}
+		if err != nil || !reflect.DeepEqual(got, want) {
+			t.Errorf("One() got = %#v (err=%v), want %#v", got, err, want)
+		}
+
+		s, err := got.SExpr()
+		if err != nil || s != "#point (1 2)" {
+			t.Errorf("SExpr() got = %s (err=%v)", s, err)
+		}
+	})
+}
+
+func TestReader_Conditional(t *testing.T) {
+	t.Parallel()
+
+	kw := func(s string) parens.Any { return parens.Keyword(s) }
+	tests := []struct {
+		name     string
+		src      string
+		features []string
+		want     parens.Any
+		wantErr  bool
+	}{
+		{
+			name:     "Feature",
+			src:      `(#?(:a 1 :b 2))`,
+			features: []string{"b"},
+			want:     parens.NewList(parens.Int64(2)),
+		},
+		{
+			name:     "FirstMatch",
+			src:      `(#?(:a 1 :b 2 :default 3))`,
+			features: []string{"b", "a"},
+			want:     parens.NewList(parens.Int64(1)),
+		},
+		{
+			name: "Default",
+			src:  `(#?(:a 1 :default 3))`,
+			want: parens.NewList(parens.Int64(3)),
+		},
+		{
+			name: "NoMatch",
+			src:  `(:x #?(:a 1) :y)`,
+			want: parens.NewList(kw("x"), kw("y")),
+		},
+		{
+			name:     "Splicing",
+			src:      `(:x #?@(:a (1 2) :b (3)) :y)`,
+			features: []string{"a"},
+			want:     parens.NewList(kw("x"), parens.Int64(1), parens.Int64(2), kw("y")),
+		},
+		{
+			name:     "UnknownTagInSkippedBranch",
+			src:      `(#?(:a #point (1 2) :b :ok))`,
+			features: []string{"b"},
+			want:     parens.NewList(kw("ok")),
+		},
+		{
+			name:     "UnknownTagInSelectedBranch",
+			src:      `(#?(:a #point (1 2) :b :ok))`,
+			features: []string{"a"},
+			wantErr:  true,
+		},
+		{
+			name:    "SplicingTopLevel",
+			src:     `#?@(:default (1 2))`,
+			wantErr: true,
+		},
+		{
+			name:    "SplicingNotList",
+			src:     `(#?@(:default 1))`,
+			wantErr: true,
+		},
+		{
+			name:    "OddForms",
+			src:     `(#?(:a 1 :b))`,
+			wantErr: true,
+		},
+		{
+			name:    "NotKeyword",
+			src:     `(#?(a 1))`,
+			wantErr: true,
+		},
+		{
+			name:    "NotList",
+			src:     `#?:a`,
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := New(strings.NewReader(tt.src), WithFeatures(tt.features...)).One()
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("One() error = %v, wantErr %v", err, tt.wantErr)
+			} else if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("One() got = %#v, want %#v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestReader_Meta(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		src     string
+		want    string
+		meta    string
+		wantErr bool
+	}{
+		{src: `^:private x`, want: "x", meta: "{:private true}"},
+		{src: `^String x`, want: "x", meta: "{:tag String}"},
+		{src: `^"[]int" x`, want: "x", meta: `{:tag "[]int"}`},
+		{src: `^{:a 1 :b 2} (x)`, want: "(x)", meta: "{:a 1, :b 2}"},
+		{src: `^:a ^{:a 2 :b 1} (x)`, want: "(x)", meta: "{:a true, :b 1}"},
+		{src: `^:a {}`, want: "{}", meta: "{:a true}"},
+		{src: `^:a 1`, wantErr: true},
+		{src: `^1 x`, wantErr: true},
+		{src: `^{:a} x`, wantErr: true},
+		{src: `^:a`, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		got, err := New(strings.NewReader(tt.src)).One()
+		if (err != nil) != tt.wantErr {
+			t.Errorf("%s: error = %v, wantErr %v", tt.src, err, tt.wantErr)
+			continue
+		} else if tt.wantErr {
+			continue
+		}
+
+		s, _ := got.SExpr()
+		meta, _ := got.(parens.Annotated).Meta().SExpr()
+		if s != tt.want || meta != tt.meta {
+			t.Errorf("%s: got %s with meta %s, want %s with meta %s", tt.src, s, meta, tt.want, tt.meta)
+		}
+	}
+
+	got, err := New(strings.NewReader("(a\n ^:x (b))"), WithPositions()).One()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	next, _ := got.(parens.Seq).Next()
+	inner, _ := next.First()
+	if pos := inner.(parens.Positional).Pos(); pos.Ln != 2 || pos.Col != 6 {
+		t.Errorf("position of annotated list not retained: %v", pos)
+	}
+}
+
+func TestReader_AllRecovering(t *testing.T) {
+	t.Parallel()
+
+	src := `(def a 1)
+(def b 1x2 (c))
+)
+(def c "abc\q" d) ; comment (
+(def d 4)
+(def e "unterminated)
+(def f 6)
+(def g (h)
+(def i 9)
+(def j #{)`
+
+	rd := New(strings.NewReader(src))
+	rd.File = "lint.lisp"
+	forms, errs := rd.AllRecovering()
+
+	var got []string
+	for _, form := range forms {
+		s, err := form.SExpr()
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		got = append(got, s)
+	}
+
+	want := []string{"(def a 1)", "(def d 4)", "(def f 6)", "(def i 9)"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("AllRecovering() forms = %v, want %v", got, want)
+	}
+
+	pos := func(ln, col int) Position { return Position{File: "lint.lisp", Ln: ln, Col: col} }
+	wantErrs := []struct {
+		cause      error
+		begin, end Position
+	}{
+		{ErrNumberFormat, pos(2, 8), pos(2, 10)},
+		{ErrUnmatchedDelimiter, pos(3, 1), pos(3, 1)},
+		{ErrInvalidEscape, pos(4, 12), pos(4, 13)},
+		{ErrEOF, pos(6, 8), pos(6, 21)},
+		{ErrEOF, pos(8, 1), pos(8, 10)},
+		{ErrUnmatchedDelimiter, pos(10, 10), pos(10, 10)},
+	}
+	if len(errs) != len(wantErrs) {
+		t.Fatalf("AllRecovering() errors = %v, want %d errors", errs, len(wantErrs))
+	}
+	for i, want := range wantErrs {
+		if !errors.Is(errs[i], want.cause) || errs[i].Begin != want.begin || errs[i].End != want.end {
+			t.Errorf("error %d = %v (%v - %v), want %v (%v - %v)",
+				i, errs[i], errs[i].Begin, errs[i].End, want.cause, want.begin, want.end)
+		}
+	}
+
+	if form, err := rd.One(); err != io.EOF {
+		t.Errorf("expecting EOF after AllRecovering(), got %v, %v", form, err)
+	}
+}
+
+func TestReadCST(t *testing.T) {
+	t.Parallel()
+
+	src := "; rules\n(def ^:private x ,#_old 'y)\n"
+	root, err := ReadCST(strings.NewReader(src))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	var got []string
+	var walk func(n *Node, depth int)
+	walk = func(n *Node, depth int) {
+		got = append(got, fmt.Sprintf("%s%s %q %d:%d-%d:%d", strings.Repeat(" ", depth), n.Kind, n.Text,
+			n.Begin.Ln, n.Begin.Col, n.End.Ln, n.End.Col))
+		for _, child := range n.Children {
+			walk(child, depth+1)
+		}
+	}
+	walk(root, 0)
+
+	want := []string{
+		`root "" 1:1-2:28`,
+		` comment "; rules" 1:1-1:7`,
+		` whitespace "\n" 1:8-1:8`,
+		` list "(" 2:1-2:27`,
+		`  token "def" 2:2-2:4`,
+		`  whitespace " " 2:5-2:5`,
+		`  prefixed "^" 2:6-2:16`,
+		`   token ":private" 2:7-2:14`,
+		`   whitespace " " 2:15-2:15`,
+		`   token "x" 2:16-2:16`,
+		`  whitespace " ," 2:17-2:18`,
+		`  discard "#_" 2:19-2:23`,
+		`   token "old" 2:21-2:23`,
+		`  whitespace " " 2:24-2:24`,
+		`  prefixed "'" 2:25-2:26`,
+		`   token "y" 2:26-2:26`,
+		` whitespace "\n" 2:28-2:28`,
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("ReadCST() got tree:\n%s\nwant:\n%s", strings.Join(got, "\n"), strings.Join(want, "\n"))
+	}
+}
+
+func TestReadCST_Lossless(t *testing.T) {
+	t.Parallel()
+
+	sources := []string{
+		"",
+		"  \n\t,",
+		"; only a comment",
+		"#?@(:a (1 2) :default ()) #?(:b 1) #(+ %1 %&) #\"a\\\"b\" ##-Inf \\space \\( #uuid \"0-0\"",
+		"^{:doc \"d\"} ^String (f) `(a ~b) '#_c d",
+		"(\"\xff\xfe\" ; \xc3\n \xe2\x82)\r\n",
+	}
+
+	rnd := rand.New(rand.NewSource(2))
+	trivia := []string{" ", "\n", ",", " ; comment (\n", "\t#_ discarded ", "\r\n"}
+	for i := 0; i < 500; i++ {
+		var b strings.Builder
+		for j := rnd.Intn(5); j >= 0; j-- {
+			s, err := randomValue(rnd, 2).SExpr()
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			b.WriteString(trivia[rnd.Intn(len(trivia))])
+			b.WriteString(s)
+		}
+		sources = append(sources, b.String())
+	}
+
+	for _, src := range sources {
+		root, err := ReadCST(strings.NewReader(src))
+		if err != nil {
+			t.Fatalf("%q: unexpected error: %v", src, err)
+		} else if got := root.String(); got != src {
+			t.Fatalf("ReadCST(%q).String() = %q", src, got)
+		}
+
+		forms, err := New(strings.NewReader(src)).All()
+		if err != nil {
+			continue
+		}
+
+		var got []parens.Any
+		for _, node := range root.Children {
+			if node.IsTrivia() {
+				continue
+			}
+
+			form, err := node.Form()
+			if err == ErrSkip || err == io.EOF {
+				continue
+			} else if err != nil {
+				t.Fatalf("%q: Form() of %q failed: %v", src, node, err)
+			}
+			got = append(got, form)
+		}
+		if !reflect.DeepEqual(got, forms) {
+			t.Fatalf("%q: forms of nodes = %#v, want %#v", src, got, forms)
+		}
+	}
+}
+
+func TestReadCST_Errors(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		src        string
+		cause      error
+		begin, end Position
+	}{
+		{src: "(a\n (b)", cause: ErrEOF, begin: Position{Ln: 1, Col: 1}, end: Position{Ln: 2, Col: 4}},
+		{src: "a)", cause: ErrUnmatchedDelimiter, begin: Position{Ln: 1, Col: 2}, end: Position{Ln: 1, Col: 2}},
+		{src: "{:a \"b}", cause: ErrEOF, begin: Position{Ln: 1, Col: 5}, end: Position{Ln: 1, Col: 7}},
+		{src: "'", cause: ErrEOF, begin: Position{Ln: 1, Col: 1}, end: Position{Ln: 1, Col: 1}},
+		{src: "(^:a)", cause: ErrUnmatchedDelimiter, begin: Position{Ln: 1, Col: 5}, end: Position{Ln: 1, Col: 5}},
+	}
+
+	for _, tt := range tests {
+		_, err := ReadCST(strings.NewReader(tt.src))
+		tt.begin.File, tt.end.File = "<string>", "<string>"
+
+		e, ok := err.(Error)
+		if !ok || !errors.Is(err, tt.cause) || e.Begin != tt.begin || e.End != tt.end {
+			t.Errorf("%q: got error %v (%v - %v), want %v (%v - %v)",
+				tt.src, err, e.Begin, e.End, tt.cause, tt.begin, tt.end)
+		}
+	}
+}
+
+func TestStream(t *testing.T) {
+	t.Parallel()
+
+	s := NewStream(nil)
+	feed := func(chunk string, want string, pending bool) {
+		t.Helper()
+
+		forms, err := s.Feed(chunk)
+		if err != nil {
+			t.Fatalf("Feed(%q): unexpected error: %v", chunk, err)
+		}
+
+		var got []string
+		for _, form := range forms {
+			sexpr, err := form.SExpr()
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			got = append(got, sexpr)
+		}
+		if strings.Join(got, " ") != want || s.Pending() != pending {
+			t.Fatalf("Feed(%q) = %q (pending %t), want %q (pending %t)",
+				chunk, got, s.Pending(), want, pending)
+		}
+	}
+
+	feed("(def a 1) (b", "(def a 1)", true)
+	feed(" ; c)\n", "", true)
+	feed("\"d)\"", "", true)
+	feed(")\n\n", `(b "d)")`, false)
+	feed("abc", "", true)
+	feed("d ^:m x", "abcd", true)
+	feed(" '#inst \"2020-01-01T00:00:00Z\"", `x (quote #inst "2020-01-01T00:00:00Z")`, false)
+	feed("#_(e) f \\)", "f", true)
+	feed(" #?(:default 1) #?(:b 2)", "\\) 1", false)
+
+	forms, err := s.Flush()
+	if err != nil || len(forms) != 0 {
+		t.Fatalf("Flush() = %v, %v", forms, err)
+	}
+
+	_, err = s.Feed("\n(g 1x2 h) (i)\n(j")
+	e, ok := err.(Error)
+	if !ok || !errors.Is(err, ErrNumberFormat) || e.Begin.Ln != 5 || e.Begin.Col != 4 {
+		t.Fatalf("Feed() error = %#v, want ErrNumberFormat at 5:4", err)
+	}
+	feed("", "(i)", true)
+
+	_, err = s.Flush()
+	if !errors.Is(err, ErrEOF) {
+		t.Fatalf("Flush() error = %v, want ErrEOF", err)
+	}
+
+	s.Reset()
+	feed("(k (", "", true)
+	s.Reset()
+	feed("(l)", "(l)", false)
+}
+
+func TestStream_Chunks(t *testing.T) {
+	t.Parallel()
+
+	sources := []string{
+		"(#?@(:a (1 2) :default ())) #?(:b 1) #(+ %1 %&) #\"a\\\"b\" ##-Inf \\space \\( #uuid \"6ba7b810-9dad-11d1-80b4-00c04fd430c8\"",
+		"^{:doc \"d\"} ^String (f) `(a ~b) '#_c d",
+		"(\"\xff\xfe\" ; \xc3\n \xe2\x82)\r\n",
+	}
+
+	rnd := rand.New(rand.NewSource(3))
+	trivia := []string{" ", "\n", ",", " ; comment (\n", "\t#_ discarded ", "\r\n"}
+	for i := 0; i < 300; i++ {
+		var b strings.Builder
+		for j := rnd.Intn(5); j >= 0; j-- {
+			s, err := randomValue(rnd, 2).SExpr()
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			b.WriteString(trivia[rnd.Intn(len(trivia))])
+			b.WriteString(s)
+		}
+		sources = append(sources, b.String())
+	}
+
+	for _, src := range sources {
+		want, err := New(strings.NewReader(src)).All()
+		if err != nil {
+			t.Fatalf("%q: unexpected error: %v", src, err)
+		}
+
+		s := NewStream(nil)
+		var got []parens.Any
+		for rest := src; len(rest) > 0; {
+			n := 1 + rnd.Intn(8)
+			if n > len(rest) {
+				n = len(rest)
+			}
+
+			forms, err := s.Feed(rest[:n])
+			if err != nil {
+				t.Fatalf("%q: Feed(%q) failed: %v", src, rest[:n], err)
+			}
+			got = append(got, forms...)
+			rest = rest[n:]
+		}
+
+		forms, err := s.Flush()
+		if err != nil {
+			t.Fatalf("%q: Flush() failed: %v", src, err)
+		}
+		got = append(got, forms...)
+
+		if !reflect.DeepEqual(got, want) {
+			t.Fatalf("%q: forms = %#v, want %#v", src, got, want)
+		}
+	}
+}
+
+func TestStream_CustomMacro(t *testing.T) {
+	t.Parallel()
+
+	// `|...|` reads the runes up to the next `|` (including spaces) as a
+	// symbol, and `[...]` reads a list.
+	newReader := func(r io.Reader) *Reader {
+		rd := New(r)
+		rd.SetMacro('|', false, func(rd *Reader, _ rune) (parens.Any, error) {
+			var name []rune
+			for {
+				r, err := rd.NextRune()
+				if err != nil {
+					return nil, rd.annotateErr(ErrEOF, rd.Position(), string(name))
+				} else if r == '|' {
+					return parens.Symbol(name), nil
+				}
+				name = append(name, r)
+			}
+		})
+		rd.SetMacro('[', false, func(rd *Reader, _ rune) (parens.Any, error) {
+			var items []parens.Any
+			err := rd.Container(']', "vector", func(form parens.Any) error {
+				items = append(items, form)
+				return nil
+			})
+			return parens.NewList(items...), err
+		})
+		rd.SetMacro(']', false, UnmatchedDelimiter())
+		return rd
+	}
+
+	src := "(a |b c| [d |e ) f|]) |g h| [i\n j]"
+	want, err := newReader(strings.NewReader(src)).All()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	for size := 1; size <= 4; size++ {
+		s := NewStream(newReader)
+		var got []parens.Any
+		for rest := src; len(rest) > 0; {
+			n := size
+			if n > len(rest) {
+				n = len(rest)
+			}
+
+			forms, err := s.Feed(rest[:n])
+			if err != nil {
+				t.Fatalf("Feed(%q) failed: %v", rest[:n], err)
+			}
+			got = append(got, forms...)
+			rest = rest[n:]
+		}
+
+		forms, err := s.Flush()
+		if err != nil {
+			t.Fatalf("Flush() failed: %v", err)
+		}
+		got = append(got, forms...)
+
+		if !reflect.DeepEqual(got, want) {
+			t.Fatalf("chunks of %d: forms = %#v, want %#v", size, got, want)
+		}
+	}
+}
+
+func TestStream_ReadOnce(t *testing.T) {
+	t.Parallel()
+
+	reads := 0
+	s := NewStream(func(r io.Reader) *Reader {
+		rd := New(r)
+		rd.SetMacro('|', false, func(rd *Reader, _ rune) (parens.Any, error) {
+			reads++
+			token, err := rd.Token(-1)
+			return parens.Symbol(token), err
+		})
+		return rd
+	})
+
+	const lines = 1000
+	for i := 0; i < lines; i++ {
+		chunk := "|a\n"
+		if i == 0 {
+			chunk = "(" + chunk
+		}
+
+		forms, err := s.Feed(chunk)
+		if err != nil || len(forms) != 0 || !s.Pending() {
+			t.Fatalf("Feed(%q) = %v, %v (pending %t)", chunk, forms, err, s.Pending())
+		}
+	}
+
+	forms, err := s.Feed(")")
+	if err != nil || len(forms) != 1 || s.Pending() {
+		t.Fatalf("Feed(\")\") = %v, %v (pending %t)", forms, err, s.Pending())
+	}
+
+	if reads != lines {
+		t.Errorf("macro invoked %d times, want %d", reads, lines)
+	}
+}
+
+// TestReader_RoundTrip verifies that every value printed using SExpr()
+// reads back as the same value.
+func TestReader_RoundTrip(t *testing.T) {
+	t.Parallel()
+
+	rnd := rand.New(rand.NewSource(1))
+	for i := 0; i < 5000; i++ {
+		v := randomValue(rnd, 2)
+		s, err := v.SExpr()
+		if err != nil {
+			t.Fatalf("SExpr() of %#v failed: %v", v, err)
+		}
+
+		got, err := New(strings.NewReader(s)).One()
+		if err != nil {
+			t.Fatalf("%s: unexpected error: %v", s, err)
+		} else if !reflect.DeepEqual(got, v) {
+			t.Fatalf("%s: read back as %#v, want %#v", s, got, v)
+		}
+	}
+}
+
+func randomValue(rnd *rand.Rand, depth int) parens.Any {
+	n := 12
+	if depth > 0 {
+		n = 14
+	}
+
+	switch rnd.Intn(n) {
+	case 0:
+		return parens.Nil{}
+	case 1:
+		return parens.Bool(rnd.Intn(2) == 0)
+	case 2:
+		return randomNumber(rnd)
+	case 3:
+		return randomFloat(rnd)
+	case 4:
+		r := rnd.Int31n(unicode.MaxRune + 1)
+		if utf16.IsSurrogate(r) {
+			r = unicode.ReplacementChar // surrogates are not valid characters.
+		}
+		return parens.Char(r)
+	case 5:
+		return parens.String(randomString(rnd))
+	case 6:
+		return parens.Symbol("s" + randomName(rnd))
+	case 7:
+		return parens.Keyword(randomName(rnd))
+	case 8:
+		return parens.Char([]rune(" \t\n\r\b\f\\\"();,#{}a")[rnd.Intn(16)])
+	case 9:
+		patterns := []string{`a+b`, `\d{2}\"x`, `(?i)[a-z]\\`, ``}
+		return mustRegex(patterns[rnd.Intn(len(patterns))])
+	case 10:
+		sec := rnd.Int63n(1 << 35)
+		return parens.Inst{Time: time.Unix(sec, rnd.Int63n(1e9)).UTC()}
+	case 11:
+		var id parens.UUID
+		rnd.Read(id[:])
+		return id
+	case 12:
+		items := make([]parens.Any, rnd.Intn(4))
+		for i := range items {
+			items[i] = randomValue(rnd, depth-1)
+		}
+		return parens.NewList(items...)
+	default:
+		var kvs []parens.Any
+		for i := rnd.Intn(4); i > 0; i-- {
+			kvs = append(kvs, parens.Keyword(randomName(rnd)), randomValue(rnd, depth-1))
+		}
+		return mustHashMap(kvs...)
+	}
+}
+
+// randomNumber returns an Int64, a BigInt, a Ratio or a Decimal. Big
+// numbers are never zero since the representation of zero read back by the
+// reader need not be deeply equal.
+func randomNumber(rnd *rand.Rand) parens.Any {
+	n := new(big.Int).Rand(rnd, new(big.Int).Lsh(big.NewInt(1), uint(1+rnd.Intn(128))))
+	n.Add(n, big.NewInt(1))
+	if rnd.Intn(2) == 0 {
+		n.Neg(n)
+	}
+
+	switch rnd.Intn(4) {
+	case 0:
+		return parens.Int64(rnd.Uint64())
+	case 1:
+		return parens.BigInt{Int: n}
+	case 2:
+		v, err := parens.NewRatio(n, big.NewInt(1+rnd.Int63n(1000)))
+		if err != nil {
+			panic(err)
+		}
+		return v
+	default:
+		d, err := parens.ParseDecimal(fmt.Sprintf("%se-%d", n, rnd.Intn(10)))
+		if err != nil {
+			panic(err)
+		}
+		return d
+	}
+}
+
+func randomFloat(rnd *rand.Rand) parens.Float64 {
+	switch rnd.Intn(4) {
+	case 0:
+		return parens.Float64(math.Inf(1 - 2*rnd.Intn(2)))
+	case 1:
+		return parens.Float64(rnd.Int63n(1000000) - 500000)
+	case 2:
+		return parens.Float64(rnd.NormFloat64())
+	}
+
+	for {
+		f := math.Float64frombits(rnd.Uint64())
+		if !math.IsNaN(f) {
+			return parens.Float64(f)
+		}
+	}
+}
+
+func randomString(rnd *rand.Rand) string {
+	var b strings.Builder
+	for i := rnd.Intn(10); i > 0; i-- {
+		switch rnd.Intn(4) {
+		case 0:
+			b.WriteByte(byte(rnd.Intn(256)))
+		case 1:
+			b.WriteRune(rnd.Int31n(unicode.MaxRune + 1))
+		default:
+			b.WriteByte(byte(rnd.Intn(128)))
+		}
+	}
+	return b.String()
+}
+
+func randomName(rnd *rand.Rand) string {
+	const chars = "abcdefghijklmnopqrstuvwxyz0123456789-?!*"
+	b := make([]byte, 1+rnd.Intn(8))
+	for i := range b {
+		b[i] = chars[rnd.Intn(len(chars))]
+	}
+	return string(b)
+}
+
+func mustHashMap(kvs ...parens.Any) *parens.HashMap {
+	hm, err := parens.NewHashMap(kvs...)
+	if err != nil {
+		panic(err)
+	}
+	return hm
+}
+
+func mustRegex(pattern string) parens.Regex {
+	re, err := parens.NewRegex(pattern)
+	if err != nil {
+		panic(err)
+	}
+	return re
+}
@@ -0,0 +1,215 @@
+package reader
+
+import (
+	"errors"
+	"io"
+	"strings"
+
+	"github.com/spy16/parens"
+)
+
+// AllRecovering is same as All() but does not stop at the first error. After
+// an error, the reader resynchronizes at the next top-level form and resumes
+// reading. Returns the forms read successfully along with the errors in the
+// order they were found.
+//
+// The remainder of a form containing an error (e.g., an invalid number or
+// an unmatched delimiter) is skipped. Since a form that is not terminated
+// (e.g., an unterminated string) consumes the forms following it, reading
+// resumes at the first line after the beginning of the form that starts with
+// '(' in the first column, if the form extends beyond such a line.
+func (rd *Reader) AllRecovering() ([]parens.Any, []Error) {
+	var forms []parens.Any
+	var errs []Error
+
+	rd.recording = true
+	defer func() {
+		rd.recording = false
+		rd.tape = nil
+	}()
+
+	for {
+		if err := rd.SkipSpaces(); err != nil {
+			if !errors.Is(err, io.EOF) {
+				errs = append(errs, Error{Cause: err, Begin: rd.Position(), End: rd.Position()})
+			}
+			break
+		}
+		rd.tape = rd.tape[:0]
+
+		form, err := rd.One()
+		if err != nil {
+			if errors.Is(err, io.EOF) {
+				break
+			}
+			errs = append(errs, rd.resync(err))
+			continue
+		}
+		forms = append(forms, form)
+	}
+
+	return forms, errs
+}
+
+// resync skips the input until the beginning of the next top-level form
+// after the error in the form recorded on the tape. Returns the error with
+// the positions set.
+func (rd *Reader) resync(err error) Error {
+	readErr, ok := err.(Error)
+	if !ok {
+		readErr = Error{Cause: err}
+	}
+
+	begin := rd.Position()
+	if len(rd.tape) > 0 {
+		begin = rd.tapePos(0)
+	}
+	if readErr.Begin == (Position{}) {
+		readErr.Begin, readErr.End = begin, rd.Position()
+	}
+
+	// a line starting with '(' within the form suggests that the form is not
+	// terminated before the next top-level form.
+	for i := 1; i < len(rd.tape); i++ {
+		if rd.tape[i].r != '(' || rd.tape[i-1].r != '\n' {
+			continue
+		}
+
+		j := i - 1
+		for j > 0 && isSpace(rd.tape[j].r) {
+			j--
+		}
+
+		end := rd.tapePos(j)
+		if !isBefore(readErr.Begin, end) {
+			// error is in the forms following the resync point and will be
+			// found again. report the unterminated form instead.
+			readErr = Error{Cause: ErrEOF, Begin: begin}
+		}
+		if readErr.End == (Position{}) || isBefore(end, readErr.End) {
+			readErr.End = end
+		}
+		if k := strings.Index(readErr.Form, "\n("); k >= 0 {
+			readErr.Form = readErr.Form[:k]
+		}
+
+		rd.rewind(i)
+		return readErr
+	}
+
+	if errors.Is(err, ErrEOF) {
+		// rest of the stream has been consumed.
+		return readErr
+	}
+
+	var sync syncState
+	for _, tr := range rd.tape {
+		sync.feed(tr.r)
+	}
+
+	for !sync.idle() {
+		r, err := rd.NextRune()
+		if err != nil {
+			break
+		}
+		sync.feed(r)
+
+		if r == '\n' {
+			next, err := rd.NextRune()
+			if err != nil {
+				break
+			}
+			rd.Unread(next)
+
+			if next == '(' {
+				break
+			}
+		}
+	}
+
+	return readErr
+}
+
+// rewind returns the runes recorded on the tape from index i to the stream
+// and restores the position of the reader to that of the rune.
+func (rd *Reader) rewind(i int) {
+	runes := make([]rune, 0, len(rd.tape)-i)
+	for _, tr := range rd.tape[i:] {
+		runes = append(runes, tr.r)
+	}
+
+	t := rd.tape[i]
+	rd.buf = append(runes, rd.buf...)
+	rd.line, rd.col, rd.lastCol = t.line, t.col, t.lastCol
+	rd.tape = rd.tape[:i]
+}
+
+// tapePos returns the position of the reader after consuming the rune at
+// index i of the tape.
+func (rd *Reader) tapePos(i int) Position {
+	t := rd.tape[i]
+	pos := Position{File: strings.TrimSpace(rd.File), Ln: t.line + 1, Col: t.col + 1}
+	if t.r == '\n' {
+		pos.Ln, pos.Col = t.line+2, 0
+	}
+	return pos
+}
+
+// tapeRune is a rune consumed by the reader along with the position of the
+// reader before consuming it.
+type tapeRune struct {
+	r                  rune
+	line, col, lastCol int
+}
+
+// syncState tracks the open delimiters, strings and comments while skipping
+// the remainder of a form.
+type syncState struct {
+	open                       []rune
+	inStr, inComment, escaping bool
+}
+
+func (s *syncState) feed(r rune) {
+	switch {
+	case s.escaping:
+		s.escaping = false
+
+	case s.inComment:
+		s.inComment = r != '\n'
+
+	case s.inStr:
+		if r == '\\' {
+			s.escaping = true
+		} else if r == '"' {
+			s.inStr = false
+		}
+
+	default:
+		switch r {
+		case '\\':
+			s.escaping = true
+		case '"':
+			s.inStr = true
+		case ';':
+			s.inComment = true
+		case '(', '{':
+			s.open = append(s.open, r)
+		case ')', '}':
+			// unmatched closing delimiters are ignored.
+			if n := len(s.open); n > 0 && s.open[n-1] == openerOf[r] {
+				s.open = s.open[:n-1]
+			}
+		}
+	}
+}
+
+// idle returns true if the skipped input is not within a form.
+func (s *syncState) idle() bool {
+	return len(s.open) == 0 && !s.inStr && !s.escaping
+}
+
+var openerOf = map[rune]rune{')': '(', '}': '{'}
+
+func isBefore(p1, p2 Position) bool {
+	return p1.Ln < p2.Ln || (p1.Ln == p2.Ln && p1.Col < p2.Col)
+}
@@ -0,0 +1,202 @@
+package reader
+
+import (
+	"errors"
+	"io"
+	"unicode/utf8"
+
+	"github.com/spy16/parens"
+)
+
+// NewStream returns a new Stream reading the forms using the Reader returned
+// by newReader for the source of the stream. newReader is called once and
+// defaults to New() with "<stream>" as the file name.
+func NewStream(newReader func(r io.Reader) *Reader) *Stream {
+	if newReader == nil {
+		newReader = func(r io.Reader) *Reader {
+			rd := New(r)
+			rd.File = "<stream>"
+			return rd
+		}
+	}
+
+	s := &Stream{resume: make(chan bool), yield: make(chan struct{})}
+	s.src.resume, s.src.yield = s.resume, s.yield
+	s.rd = newReader(&s.src)
+	s.rd.rs = &s.src
+	s.rd.recording = true
+	return s
+}
+
+// Stream is an incremental reader. Source is fed to the stream in chunks
+// using Feed() and the top-level forms are returned as soon as they are
+// complete. Forms are read using the Reader, hence custom macros are
+// supported and the positions of the forms are relative to the beginning
+// of the stream.
+//
+// Forms are read in a goroutine that waits for more input when the input
+// fed so far ends within a form. Hence the partially read form is kept
+// across calls to Feed() and its input is read only once. The goroutine
+// exits when the input ends between forms or the stream is Reset().
+type Stream struct {
+	rd     *Reader
+	src    feed
+	resume chan bool     // wakes the reading goroutine; false aborts it.
+	yield  chan struct{} // returns control from the reading goroutine.
+
+	running bool       // reading goroutine is waiting for more input.
+	skip    *syncState // rest of the form that failed to read.
+	forms   []parens.Any
+	err     error
+	panic   interface{}
+}
+
+// Feed feeds the chunk of source to the stream and returns the top-level
+// forms completed by it. Reading stops at the first error, the form causing
+// the error is discarded and the forms following it are read on the next
+// call to Feed() (with an empty chunk to read them without new input).
+func (s *Stream) Feed(chunk string) ([]parens.Any, error) {
+	s.src.buf = append(s.src.buf, chunk...)
+	return s.read()
+}
+
+// Flush reads the remaining input as if the stream ended. Returns an Error
+// with ErrEOF cause if the input ends within a form.
+func (s *Stream) Flush() ([]parens.Any, error) {
+	s.src.final = true
+	defer func() { s.src.final = false }()
+	return s.read()
+}
+
+// Pending returns true if the input fed to the stream ends within a form
+// and more input is needed to complete the form.
+func (s *Stream) Pending() bool {
+	return s.running || s.skip != nil
+}
+
+// Reset discards the input that has not been read and the partially read
+// form, if any.
+func (s *Stream) Reset() {
+	if s.running {
+		s.resume <- false
+		<-s.yield
+	}
+
+	s.src.buf = s.src.buf[:0]
+	s.src.aborted = false
+	s.rd.buf = nil
+	s.rd.tape = s.rd.tape[:0]
+	s.skip, s.forms, s.err, s.panic = nil, nil, nil, nil
+}
+
+// read hands control to the reading goroutine until it needs more input or
+// exits, and returns the forms read meanwhile.
+func (s *Stream) read() ([]parens.Any, error) {
+	if s.running {
+		s.resume <- true
+	} else {
+		s.running = true
+		go s.run()
+	}
+	<-s.yield
+
+	if p := s.panic; p != nil {
+		s.panic = nil
+		panic(p)
+	}
+
+	forms, err := s.forms, s.err
+	s.forms, s.err = nil, nil
+	return forms, err
+}
+
+func (s *Stream) run() {
+	defer func() {
+		s.panic = recover()
+		s.running = false
+		s.yield <- struct{}{}
+	}()
+
+	if s.skip != nil && !s.skipRest() {
+		return
+	}
+
+	for {
+		// end of the input between forms is the end of the reading.
+		s.src.block = false
+		if err := s.rd.SkipSpaces(); err != nil {
+			return
+		}
+
+		s.src.block = true
+		s.rd.tape = s.rd.tape[:0]
+		form, err := s.rd.topLevel()
+		if s.src.aborted {
+			return
+		} else if errors.Is(err, ErrSkip) {
+			continue
+		} else if err != nil {
+			s.err = err
+			if !s.src.final {
+				s.skip = &syncState{}
+				for _, tr := range s.rd.tape {
+					s.skip.feed(tr.r)
+				}
+			}
+			return
+		}
+		s.forms = append(s.forms, form)
+	}
+}
+
+// skipRest discards the input until the end of the form that failed to read.
+// Returns false if the stream is aborted or ends within the form.
+func (s *Stream) skipRest() bool {
+	s.src.block = true
+	for !s.skip.idle() {
+		r, err := s.rd.NextRune()
+		if err != nil {
+			return false
+		}
+		s.skip.feed(r)
+	}
+	s.skip = nil
+	return true
+}
+
+// feed is the source of a Stream. At the end of the input, a blocking feed
+// returns control to the Stream and waits until more input is fed. Runes
+// split across chunks are not read until they are complete.
+type feed struct {
+	buf     []byte
+	final   bool // no more input will be fed.
+	block   bool // wait for more input at the end of the buffer.
+	aborted bool // stream was reset while waiting for more input.
+	resume  <-chan bool
+	yield   chan<- struct{}
+}
+
+func (f *feed) ReadRune() (rune, int, error) {
+	for !f.aborted && f.block && !f.final && !utf8.FullRune(f.buf) {
+		f.yield <- struct{}{}
+		f.aborted = !<-f.resume
+	}
+
+	if f.aborted || len(f.buf) == 0 || (!f.final && !utf8.FullRune(f.buf)) {
+		return -1, 0, io.EOF
+	}
+
+	r, size := utf8.DecodeRune(f.buf)
+	f.buf = f.buf[size:]
+	return r, size, nil
+}
+
+func (f *feed) Read(p []byte) (int, error) {
+	if len(f.buf) == 0 {
+		return 0, io.EOF
+	}
+
+	n := copy(p, f.buf)
+	f.buf = f.buf[n:]
+	return n, nil
+}
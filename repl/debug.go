@@ -0,0 +1,167 @@
+package repl
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+
+	"github.com/spy16/parens"
+)
+
+const debugHelp = `commands:
+  :c  :continue   resume until the next breakpoint
+  :s  :step       step into the next invocation
+  :n  :next       step over the invocation
+  :o  :out        step out of the current function
+  :bt :frames     print the stack frames
+  :locals         print the locals of the paused frame
+  :abort          abort the evaluation
+other forms are evaluated in the paused frame.`
+
+// WithDebugger makes the REPL drop into a nested debug prompt whenever the
+// debugger pauses the evaluation. Forms entered in the debug prompt are
+// evaluated in the paused stack frame. `(break "name")` and `(unbreak
+// "name")` functions are defined in the env of the REPL to manage the
+// breakpoints. The debugger must be attached to the env of the REPL (See
+// parens.WithDebugger()).
+func WithDebugger(d *parens.Debugger) Option {
+	brk := parens.GoFunc(func(_ *parens.Env, args ...parens.Any) (parens.Any, error) {
+		for _, arg := range args {
+			spec, ok := arg.(parens.String)
+			if !ok {
+				return nil, fmt.Errorf("break: expecting breakpoint string, not '%v'", arg)
+			}
+			d.SetBreakpoint(string(spec))
+		}
+		return breakpoints(d), nil
+	})
+
+	unbrk := parens.GoFunc(func(_ *parens.Env, args ...parens.Any) (parens.Any, error) {
+		for _, arg := range args {
+			spec, ok := arg.(parens.String)
+			if !ok {
+				return nil, fmt.Errorf("unbreak: expecting breakpoint string, not '%v'", arg)
+			}
+			d.ClearBreakpoint(string(spec))
+		}
+		return breakpoints(d), nil
+	})
+
+	return func(repl *REPL) {
+		repl.define("break", brk)
+		repl.define("unbreak", unbrk)
+		d.SetHandler(repl.debug)
+	}
+}
+
+// debug runs the nested prompt for the stop until a command resumes the
+// evaluation.
+func (repl *REPL) debug(stop *parens.Stop) parens.Action {
+	where := stop.Breakpoint
+	if where == "" {
+		where = "step"
+	}
+	if stop.Pos.IsZero() {
+		fmt.Fprintf(repl.output, "[%s] paused at (%s ...)\n", where, stop.Name)
+	} else {
+		fmt.Fprintf(repl.output, "[%s] paused at (%s ...) %s\n", where, stop.Name, stop.Pos)
+	}
+	for i, arg := range stop.Args {
+		fmt.Fprintf(repl.output, "  arg %d: %v\n", i, arg)
+	}
+
+	setPrompt := func(multiline bool) {
+		if multiline {
+			repl.input.SetPrompt(fmt.Sprintf("debug[%d]%s ", stop.Depth, repl.multiPrompt))
+		} else {
+			repl.input.SetPrompt(fmt.Sprintf("debug[%d]> ", stop.Depth))
+		}
+	}
+	defer repl.setPrompt(false)
+
+	for {
+		forms, err := repl.readWith(setPrompt)
+		if err == io.EOF {
+			// input is gone. there is no way to resume.
+			return parens.Abort
+		} else if err != nil {
+			_ = repl.print(err)
+			continue
+		}
+
+		if len(forms) == 1 {
+			if kw, ok := forms[0].(parens.Keyword); ok {
+				if action, resume := repl.debugCommand(stop, string(kw)); resume {
+					return action
+				}
+				continue
+			}
+		}
+
+		for _, form := range forms {
+			res, err := stop.Eval(form)
+			if err != nil {
+				_ = repl.print(err)
+				break
+			}
+			_ = repl.print(res)
+		}
+	}
+}
+
+// debugCommand runs the debug prompt command. Returns true if the command
+// resumes the evaluation.
+func (repl *REPL) debugCommand(stop *parens.Stop, cmd string) (parens.Action, bool) {
+	switch cmd {
+	case "c", "continue":
+		return parens.Continue, true
+
+	case "s", "step":
+		return parens.StepIn, true
+
+	case "n", "next":
+		return parens.StepOver, true
+
+	case "o", "out":
+		return parens.StepOut, true
+
+	case "abort":
+		return parens.Abort, true
+
+	case "bt", "frames":
+		for i, f := range stop.Frames() {
+			fmt.Fprintf(repl.output, "#%d %s %s\n", i, f.Name, f.Pos)
+		}
+		fmt.Fprintf(repl.output, "#%d <top-level>\n", stop.Depth)
+
+	case "locals":
+		frames := stop.Frames()
+		if len(frames) == 0 {
+			fmt.Fprintln(repl.output, "no locals at top-level")
+			break
+		}
+
+		names := make([]string, 0, len(frames[0].Locals))
+		for name := range frames[0].Locals {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			fmt.Fprintf(repl.output, "%s = %v\n", name, frames[0].Locals[name])
+		}
+
+	default:
+		fmt.Fprintln(repl.output, strings.TrimSpace(debugHelp))
+	}
+	return parens.Continue, false
+}
+
+func breakpoints(d *parens.Debugger) parens.Any {
+	specs := d.Breakpoints()
+	vals := make([]parens.Any, len(specs))
+	for i, spec := range specs {
+		vals[i] = parens.String(spec)
+	}
+	return parens.NewList(vals...)
+}
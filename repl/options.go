@@ -2,9 +2,11 @@ package repl
 
 import (
 	"bufio"
+	"fmt"
 	"io"
 	"os"
 
+	"github.com/spy16/parens"
 	"github.com/spy16/parens/reader"
 )
 
@@ -106,6 +108,46 @@ func WithPrinter(p Printer) Option {
 	}
 }
 
+// WithProfiler defines the `profile-start` and `profile-stop` functions in
+// the env of the REPL to control the profiler. `(profile-stop "file")`
+// writes the pprof profile to the file. The profiler must be set as the
+// tracer of the env (See parens.WithTracer()).
+func WithProfiler(p *parens.Profiler) Option {
+	start := parens.GoFunc(func(_ *parens.Env, _ ...parens.Any) (parens.Any, error) {
+		p.Reset()
+		p.Start()
+		return parens.Nil{}, nil
+	})
+
+	stop := parens.GoFunc(func(_ *parens.Env, args ...parens.Any) (parens.Any, error) {
+		p.Stop()
+		if len(args) != 1 {
+			return nil, fmt.Errorf("profile-stop: expecting file name")
+		}
+
+		name, ok := args[0].(parens.String)
+		if !ok {
+			return nil, fmt.Errorf("profile-stop: expecting file name, not '%v'", args[0])
+		}
+
+		f, err := os.Create(string(name))
+		if err != nil {
+			return nil, err
+		}
+		defer f.Close()
+
+		if err := p.WriteProfile(f); err != nil {
+			return nil, err
+		}
+		return name, f.Close()
+	})
+
+	return func(repl *REPL) {
+		repl.define("profile-start", start)
+		repl.define("profile-stop", stop)
+	}
+}
+
 func withDefaults(opts []Option) []Option {
 	return append([]Option{
 		WithInput(nil, nil),
@@ -3,7 +3,6 @@ package repl
 
 import (
 	"context"
-	"errors"
 	"fmt"
 	"io"
 	"strings"
@@ -24,6 +23,11 @@ func New(env *parens.Env, opts ...Option) *REPL {
 		option(repl)
 	}
 
+	repl.stream = reader.NewStream(func(r io.Reader) *reader.Reader {
+		rd := repl.factory.NewReader(r)
+		rd.File = "REPL"
+		return rd
+	})
 	return repl
 }
 
@@ -43,12 +47,16 @@ type REPL struct {
 	mapInputErr ErrMapper
 	currentNS   func() string
 	factory     ReaderFactory
+	stream      *reader.Stream
 
 	banner      string
 	prompt      string
 	multiPrompt string
 
 	printer Printer
+
+	// err is the first error from setting up the REPL using the options.
+	err error
 }
 
 // Input implementation is used by REPL to read user-input. See WithInput()
@@ -59,8 +67,14 @@ type Input interface {
 }
 
 // Loop starts the read-eval-print loop. Loop runs until context is cancelled
-// or input stream returns an irrecoverable error (See WithInput()).
+// or input stream returns an irrecoverable error (See WithInput()). Returns
+// the error without starting the loop if setting up the REPL using the
+// options failed.
 func (repl *REPL) Loop(ctx context.Context) error {
+	if repl.err != nil {
+		return repl.err
+	}
+
 	repl.printBanner()
 	repl.setPrompt(false)
 
@@ -83,7 +97,7 @@ func (repl *REPL) readEvalPrint() error {
 	forms, err := repl.read()
 	if err != nil {
 		switch err.(type) {
-		case parens.Error:
+		case parens.Error, reader.Error:
 			_ = repl.print(err)
 		default:
 			return err
@@ -105,6 +119,19 @@ func (repl *REPL) readEvalPrint() error {
 	return repl.print(res[len(res)-1])
 }
 
+// define binds the function to the name in the env of the REPL. Failures
+// are recorded to be returned by Loop().
+func (repl *REPL) define(name string, fn parens.GoFunc) {
+	if repl.err != nil {
+		return
+	}
+
+	def := parens.DefExpr{Name: name, Value: &parens.ConstExpr{Const: fn}}
+	if _, err := def.Eval(repl.rootEnv); err != nil {
+		repl.err = fmt.Errorf("repl: failed to define '%s': %w", name, err)
+	}
+}
+
 func (repl *REPL) Write(b []byte) (int, error) {
 	return repl.output.Write(b)
 }
@@ -114,38 +141,32 @@ func (repl *REPL) print(v interface{}) error {
 }
 
 func (repl *REPL) read() ([]parens.Any, error) {
-	var src string
-	lineNo := 1
+	return repl.readWith(repl.setPrompt)
+}
 
+// readWith reads forms from the input using setPrompt to set the prompt for
+// every line. Lines are read until the input completes at least one form or
+// ends with no form pending.
+func (repl *REPL) readWith(setPrompt func(multiline bool)) ([]parens.Any, error) {
 	for {
-		repl.setPrompt(lineNo > 1)
+		setPrompt(repl.stream.Pending())
 
 		line, err := repl.input.Readline()
 		err = repl.mapInputErr(err)
 		if err != nil {
+			repl.stream.Reset()
 			return nil, err
 		}
 
-		src += line + "\n"
-
-		if strings.TrimSpace(src) == "" {
-			return nil, nil
-		}
-
-		rd := repl.factory.NewReader(strings.NewReader(src))
-		rd.File = "REPL"
-
-		form, err := rd.All()
+		forms, err := repl.stream.Feed(line + "\n")
 		if err != nil {
-			if errors.Is(err, reader.ErrEOF) {
-				lineNo++
-				continue
-			}
-
+			repl.stream.Reset()
 			return nil, err
 		}
 
-		return form, nil
+		if len(forms) > 0 || !repl.stream.Pending() {
+			return forms, nil
+		}
 	}
 }
 
@@ -0,0 +1,101 @@
+package parens
+
+// Capture describes a local value copied from the enclosing stack frame into
+// the frame of a function when the function value is created.
+type Capture struct {
+	From int // slot in the enclosing frame.
+	To   int // slot in the function frame.
+}
+
+// scope tracks the local names visible to the forms being analyzed. A scope
+// allocates slots in the frame it belongs to. Multiple scopes (e.g., nested
+// let forms) can share a frame.
+type scope struct {
+	parent *scope
+	frame  *frameScope
+	names  map[string]int
+}
+
+// frameScope tracks the slots of one stack frame (e.g., function body) under
+// analysis.
+type frameScope struct {
+	outer    *scope // scope enclosing the frame. nil for top level frames.
+	root     *scope
+	slots    []string
+	captures []Capture
+}
+
+func (fs *frameScope) alloc(name string) int {
+	fs.slots = append(fs.slots, name)
+	return len(fs.slots) - 1
+}
+
+// lookup returns the slot of the local name in the frame of the scope. If
+// the name is a local of an enclosing frame, it is captured into this frame.
+func (sc *scope) lookup(name string) (int, bool) {
+	for s := sc; s != nil && s.frame == sc.frame; s = s.parent {
+		if slot, found := s.names[name]; found {
+			return slot, true
+		}
+	}
+
+	fs := sc.frame
+	if fs.outer == nil {
+		return -1, false
+	}
+
+	from, found := fs.outer.lookup(name)
+	if !found {
+		return -1, false
+	}
+
+	slot := fs.alloc(name)
+	fs.captures = append(fs.captures, Capture{From: from, To: slot})
+	fs.root.names[name] = slot
+	return slot, true
+}
+
+// pushFrame starts a scope with a new frame for the analysis and declares
+// the names in it. The slots of the names are allocated in order.
+func (env *Env) pushFrame(names ...string) (fs *frameScope, pop func()) {
+	fs = &frameScope{outer: env.scope}
+	sc := &scope{parent: env.scope, frame: fs, names: map[string]int{}}
+	fs.root = sc
+
+	for _, name := range names {
+		sc.names[name] = fs.alloc(name)
+	}
+
+	prev := env.scope
+	env.scope = sc
+	return fs, func() { env.scope = prev }
+}
+
+// pushBlock starts a nested scope in the current frame. Returns false if
+// there is no frame being analyzed.
+func (env *Env) pushBlock() (pop func(), ok bool) {
+	if env.scope == nil {
+		return nil, false
+	}
+
+	prev := env.scope
+	env.scope = &scope{parent: prev, frame: prev.frame, names: map[string]int{}}
+	return func() { env.scope = prev }, true
+}
+
+// declare allocates a slot for the local name in the current scope. Later
+// declarations shadow earlier ones.
+func (env *Env) declare(name string) int {
+	slot := env.scope.frame.alloc(name)
+	env.scope.names[name] = slot
+	return slot
+}
+
+// resolveLocal returns the slot of the local name if it is visible in the
+// scope under analysis.
+func (env *Env) resolveLocal(name string) (int, bool) {
+	if env.scope == nil {
+		return -1, false
+	}
+	return env.scope.lookup(name)
+}
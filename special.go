@@ -10,6 +10,12 @@ var (
 	_ = ParseSpecial(parseGoExpr)
 	_ = ParseSpecial(parseDefExpr)
 	_ = ParseSpecial(parseQuoteExpr)
+	_ = ParseSpecial(parseBindingExpr)
+	_ = ParseSpecial(parseFnExpr)
+	_ = ParseSpecial(parseLetExpr)
+	_ = ParseSpecial(parseIfExpr)
+	_ = ParseSpecial(parseVarExpr)
+	_ = ParseSpecial(parseUndefExpr)
 )
 
 func parseQuoteExpr(_ *Env, args Seq) (Expr, error) {
@@ -33,12 +39,13 @@ func parseQuoteExpr(_ *Env, args Seq) (Expr, error) {
 }
 
 func parseDefExpr(env *Env, args Seq) (Expr, error) {
-	if count, err := args.Count(); err != nil {
+	count, err := args.Count()
+	if err != nil {
 		return nil, err
-	} else if count != 2 {
+	} else if count != 2 && count != 3 {
 		return nil, Error{
 			Cause:   errors.New("invalid def form"),
-			Message: fmt.Sprintf("requires exactly 2 arguments, got %d", count),
+			Message: fmt.Sprintf("requires 2 or 3 arguments, got %d", count),
 		}
 	}
 
@@ -47,12 +54,14 @@ func parseDefExpr(env *Env, args Seq) (Expr, error) {
 		return nil, err
 	}
 
-	sym, ok := first.(Symbol)
+	sym, ok := asSymbol(first)
 	if !ok {
 		return nil, Error{
 			Cause:   errors.New("invalid def form"),
 			Message: fmt.Sprintf("first arg must be symbol, not '%s'", reflect.TypeOf(first)),
 		}
+	} else if err := env.checkGlobal(string(sym)); err != nil {
+		return nil, err
 	}
 
 	rest, err := args.Next()
@@ -60,23 +69,345 @@ func parseDefExpr(env *Env, args Seq) (Expr, error) {
 		return nil, err
 	}
 
+	var meta Map
+	if count == 3 {
+		doc, err := rest.First()
+		if err != nil {
+			return nil, err
+		}
+
+		if _, isStr := doc.(String); !isStr {
+			return nil, Error{
+				Cause:   errors.New("invalid def form"),
+				Message: fmt.Sprintf("docstring must be string, not '%s'", reflect.TypeOf(doc)),
+			}
+		}
+
+		if meta, err = assoc(meta, Keyword("doc"), doc); err != nil {
+			return nil, err
+		}
+
+		if rest, err = rest.Next(); err != nil {
+			return nil, err
+		}
+	}
+
+	if !env.pos.IsZero() {
+		meta, err = assoc(meta,
+			Keyword("file"), String(env.pos.File),
+			Keyword("line"), Int64(env.pos.Ln),
+		)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	// metadata of the name (e.g., `^:private`) takes precedence.
+	if meta, err = mergeMeta(meta, symbolMeta(first)); err != nil {
+		return nil, err
+	}
+
 	second, err := rest.First()
 	if err != nil {
 		return nil, err
 	}
 
-	res, err := env.Eval(second)
+	env.pending = append(env.pending, string(sym))
+	val, err := env.Analyze(second)
+	env.pending = env.pending[:len(env.pending)-1]
 	if err != nil {
 		return nil, err
 	}
 
 	return &DefExpr{
 		Name:  string(sym),
-		Value: res,
+		Value: val,
+		Meta:  meta,
 	}, nil
 }
 
-func parseGoExpr(_ *Env, args Seq) (Expr, error) {
+func parseUndefExpr(_ *Env, args Seq) (Expr, error) {
+	if count, err := args.Count(); err != nil {
+		return nil, err
+	} else if count != 1 {
+		return nil, Error{
+			Cause:   errors.New("invalid undef form"),
+			Message: fmt.Sprintf("requires exactly 1 argument, got %d", count),
+		}
+	}
+
+	first, err := args.First()
+	if err != nil {
+		return nil, err
+	}
+
+	sym, ok := asSymbol(first)
+	if !ok {
+		return nil, Error{
+			Cause:   errors.New("invalid undef form"),
+			Message: fmt.Sprintf("argument must be symbol, not '%s'", reflect.TypeOf(first)),
+		}
+	}
+
+	return &UndefExpr{Name: string(sym)}, nil
+}
+
+func parseVarExpr(env *Env, args Seq) (Expr, error) {
+	if count, err := args.Count(); err != nil {
+		return nil, err
+	} else if count != 1 {
+		return nil, Error{
+			Cause:   errors.New("invalid var form"),
+			Message: fmt.Sprintf("requires exactly 1 argument, got %d", count),
+		}
+	}
+
+	first, err := args.First()
+	if err != nil {
+		return nil, err
+	}
+
+	sym, ok := asSymbol(first)
+	if !ok {
+		return nil, Error{
+			Cause:   errors.New("invalid var form"),
+			Message: fmt.Sprintf("argument must be symbol, not '%s'", reflect.TypeOf(first)),
+		}
+	}
+
+	if err := env.checkGlobal(string(sym)); err != nil {
+		return nil, err
+	}
+
+	v, found := env.Var(string(sym))
+	if !found {
+		return nil, Error{
+			Cause:   ErrNotFound,
+			Message: string(sym),
+		}
+	}
+
+	return &ConstExpr{Const: v}, nil
+}
+
+func parseFnExpr(env *Env, args Seq) (Expr, error) {
+	fe := &FnExpr{}
+
+	first, err := args.First()
+	if err != nil {
+		return nil, err
+	}
+
+	// optional name of the function used for self-reference.
+	if sym, ok := asSymbol(first); ok {
+		fe.Name = string(sym)
+		if args, err = args.Next(); err != nil {
+			return nil, err
+		}
+		if first, err = args.First(); err != nil {
+			return nil, err
+		}
+	}
+
+	params, ok := first.(Seq)
+	if !ok {
+		return nil, Error{
+			Cause:   errors.New("invalid fn form"),
+			Message: fmt.Sprintf("parameter list must be a list, not '%s'", reflect.TypeOf(first)),
+		}
+	}
+
+	err = ForEach(params, func(item Any) (bool, error) {
+		sym, ok := asSymbol(item)
+		if !ok {
+			return false, Error{
+				Cause:   errors.New("invalid fn form"),
+				Message: fmt.Sprintf("parameter must be symbol, not '%s'", reflect.TypeOf(item)),
+			}
+		}
+		fe.Params = append(fe.Params, string(sym))
+		return false, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	for i, param := range fe.Params {
+		if param != "&" {
+			continue
+		} else if i != len(fe.Params)-2 {
+			return nil, Error{
+				Cause:   errors.New("invalid fn form"),
+				Message: "'&' must be followed by exactly one parameter",
+			}
+		}
+		fe.Variadic = true
+		fe.Params = append(fe.Params[:i], fe.Params[i+1])
+		break
+	}
+
+	locals := append([]string{}, fe.Params...)
+	if fe.Name != "" {
+		locals = append(locals, fe.Name)
+	}
+	fs, popFrame := env.pushFrame(locals...)
+	defer popFrame()
+
+	rest, err := args.Next()
+	if err != nil {
+		return nil, err
+	}
+
+	body := &DoExpr{}
+	err = ForEach(rest, func(item Any) (bool, error) {
+		expr, err := env.Analyze(item)
+		if err != nil {
+			return false, err
+		}
+		body.Exprs = append(body.Exprs, expr)
+		return false, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	fe.Body = body
+	fe.Locals = fs.slots
+	fe.Captures = fs.captures
+
+	return fe, nil
+}
+
+func parseLetExpr(env *Env, args Seq) (Expr, error) {
+	if count, err := args.Count(); err != nil {
+		return nil, err
+	} else if count < 1 {
+		return nil, Error{
+			Cause:   errors.New("invalid let form"),
+			Message: "requires binding list",
+		}
+	}
+
+	first, err := args.First()
+	if err != nil {
+		return nil, err
+	}
+
+	pairs, ok := first.(Seq)
+	if !ok {
+		return nil, Error{
+			Cause:   errors.New("invalid let form"),
+			Message: fmt.Sprintf("first arg must be a list, not '%s'", reflect.TypeOf(first)),
+		}
+	}
+
+	if count, err := pairs.Count(); err != nil {
+		return nil, err
+	} else if count%2 != 0 {
+		return nil, Error{
+			Cause:   errors.New("invalid let form"),
+			Message: "requires an even number of forms in binding list",
+		}
+	}
+
+	le := &LetExpr{}
+
+	// let at top level (outside any function) needs a frame of its own.
+	var fs *frameScope
+	pop, ok := env.pushBlock()
+	if !ok {
+		fs, pop = env.pushFrame()
+	}
+	defer pop()
+
+	var name Symbol
+	err = ForEach(pairs, func(item Any) (bool, error) {
+		if name == "" {
+			sym, ok := asSymbol(item)
+			if !ok {
+				return false, Error{
+					Cause:   errors.New("invalid let form"),
+					Message: fmt.Sprintf("binding name must be symbol, not '%s'", reflect.TypeOf(item)),
+				}
+			}
+			name = sym
+			return false, nil
+		}
+
+		// value is analyzed before declaring the name so that the value
+		// can refer to an outer binding with the same name.
+		val, err := env.Analyze(item)
+		if err != nil {
+			return false, err
+		}
+		le.Values = append(le.Values, val)
+		le.Slots = append(le.Slots, env.declare(string(name)))
+		name = ""
+		return false, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	rest, err := args.Next()
+	if err != nil {
+		return nil, err
+	}
+
+	body := &DoExpr{}
+	err = ForEach(rest, func(item Any) (bool, error) {
+		expr, err := env.Analyze(item)
+		if err != nil {
+			return false, err
+		}
+		body.Exprs = append(body.Exprs, expr)
+		return false, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	le.Body = body
+
+	if fs != nil {
+		le.Frame = append([]string{}, fs.slots...)
+	}
+	return le, nil
+}
+
+func parseIfExpr(env *Env, args Seq) (Expr, error) {
+	count, err := args.Count()
+	if err != nil {
+		return nil, err
+	} else if count != 2 && count != 3 {
+		return nil, Error{
+			Cause:   errors.New("invalid if form"),
+			Message: fmt.Sprintf("requires 2 or 3 arguments, got %d", count),
+		}
+	}
+
+	var exprs []Expr
+	err = ForEach(args, func(item Any) (bool, error) {
+		expr, err := env.Analyze(item)
+		if err != nil {
+			return true, err
+		}
+		exprs = append(exprs, expr)
+		return false, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	ife := &IfExpr{Test: exprs[0], Then: exprs[1]}
+	if len(exprs) == 3 {
+		ife.Else = exprs[2]
+	}
+
+	ife.Then = env.coverBranch(0, ife.Then)
+	ife.Else = env.coverBranch(1, ife.Else)
+	return ife, nil
+}
+
+func parseGoExpr(env *Env, args Seq) (Expr, error) {
 	v, err := args.First()
 	if err != nil {
 		return nil, err
@@ -88,5 +419,96 @@ func parseGoExpr(_ *Env, args Seq) (Expr, error) {
 		}
 	}
 
-	return GoExpr{v}, nil
+	expr, err := env.Analyze(v)
+	if err != nil {
+		return nil, err
+	}
+
+	return GoExpr{Value: expr}, nil
+}
+
+func parseBindingExpr(env *Env, args Seq) (Expr, error) {
+	if count, err := args.Count(); err != nil {
+		return nil, err
+	} else if count < 1 {
+		return nil, Error{
+			Cause:   errors.New("invalid binding form"),
+			Message: "requires binding list",
+		}
+	}
+
+	first, err := args.First()
+	if err != nil {
+		return nil, err
+	}
+
+	pairs, ok := first.(Seq)
+	if !ok {
+		return nil, Error{
+			Cause:   errors.New("invalid binding form"),
+			Message: fmt.Sprintf("first arg must be a list, not '%s'", reflect.TypeOf(first)),
+		}
+	}
+
+	if count, err := pairs.Count(); err != nil {
+		return nil, err
+	} else if count%2 != 0 {
+		return nil, Error{
+			Cause:   errors.New("invalid binding form"),
+			Message: "requires an even number of forms in binding list",
+		}
+	}
+
+	be := &BindingExpr{}
+	err = ForEach(pairs, func(item Any) (bool, error) {
+		if len(be.Names) == len(be.Values) {
+			sym, ok := asSymbol(item)
+			if !ok {
+				return false, Error{
+					Cause:   errors.New("invalid binding form"),
+					Message: fmt.Sprintf("binding name must be symbol, not '%s'", reflect.TypeOf(item)),
+				}
+			} else if !IsDynamic(string(sym)) {
+				// vars declared dynamic using metadata must exist already.
+				if v, found := env.Var(string(sym)); !found || !v.Dynamic() {
+					return false, Error{
+						Cause:   ErrNotDynamic,
+						Message: string(sym),
+					}
+				}
+			}
+			be.Names = append(be.Names, string(sym))
+			return false, nil
+		}
+
+		val, err := env.Analyze(item)
+		if err != nil {
+			return false, err
+		}
+		be.Values = append(be.Values, val)
+		return false, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	body := &DoExpr{}
+	rest, err := args.Next()
+	if err != nil {
+		return nil, err
+	}
+	err = ForEach(rest, func(item Any) (bool, error) {
+		expr, err := env.Analyze(item)
+		if err != nil {
+			return false, err
+		}
+		body.Exprs = append(body.Exprs, expr)
+		return false, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	be.Body = body
+
+	return be, nil
 }
@@ -0,0 +1,128 @@
+package parens
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+	"time"
+)
+
+var (
+	_ Tracer = NopTracer{}
+	_ Tracer = (*writerTracer)(nil)
+	_ Tracer = multiTracer(nil)
+)
+
+// Tracer observes the analysis and evaluation of forms in an Env. Tracer
+// is called synchronously and must be safe for concurrent use if the forks
+// of the env are used concurrently. See WithTracer().
+type Tracer interface {
+	// Expanded is called when macro expansion of a form produces a new
+	// form.
+	Expanded(env *Env, form, expanded Any)
+
+	// Analyzed is called after a form is analyzed using Env.Analyze(). This
+	// includes the sub-forms (e.g., call args, fn body), which are reported
+	// before the enclosing form.
+	Analyzed(env *Env, form Any, expr Expr, err error)
+
+	// InvokeStart is called before an invocation.
+	InvokeStart(env *Env, name string, args []Any)
+
+	// InvokeEnd is called after an invocation with its result and duration.
+	InvokeEnd(env *Env, name string, args []Any, res Any, err error, dur time.Duration)
+}
+
+// NopTracer implements Tracer with no-op methods. It can be embedded to
+// implement only some of the Tracer methods.
+type NopTracer struct{}
+
+// Expanded does nothing.
+func (NopTracer) Expanded(_ *Env, _, _ Any) {}
+
+// Analyzed does nothing.
+func (NopTracer) Analyzed(_ *Env, _ Any, _ Expr, _ error) {}
+
+// InvokeStart does nothing.
+func (NopTracer) InvokeStart(_ *Env, _ string, _ []Any) {}
+
+// InvokeEnd does nothing.
+func (NopTracer) InvokeEnd(_ *Env, _ string, _ []Any, _ Any, _ error, _ time.Duration) {}
+
+// NewWriterTracer returns a Tracer that writes the invocations to w as an
+// indented call tree. Each invocation is written as the call form followed
+// by the result (or error) and the time taken, indented by the depth of the
+// call. If withTime is false, time taken is not written (e.g., to compare
+// traces).
+func NewWriterTracer(w io.Writer, withTime bool) Tracer {
+	return &writerTracer{w: w, withTime: withTime}
+}
+
+type writerTracer struct {
+	NopTracer
+
+	mu       sync.Mutex
+	w        io.Writer
+	withTime bool
+}
+
+func (wt *writerTracer) InvokeStart(env *Env, name string, args []Any) {
+	var b strings.Builder
+	b.WriteString("(")
+	b.WriteString(name)
+	for _, arg := range args {
+		b.WriteString(" ")
+		b.WriteString(formString(arg))
+	}
+	b.WriteString(")")
+
+	wt.write(len(env.stack), b.String())
+}
+
+func (wt *writerTracer) InvokeEnd(env *Env, _ string, _ []Any, res Any, err error, dur time.Duration) {
+	line := "=> " + formString(res)
+	if err != nil {
+		line = "!! " + err.Error()
+	}
+	if wt.withTime {
+		line += fmt.Sprintf(" [%s]", dur)
+	}
+
+	wt.write(len(env.stack), line)
+}
+
+func (wt *writerTracer) write(depth int, line string) {
+	wt.mu.Lock()
+	defer wt.mu.Unlock()
+	_, _ = fmt.Fprintf(wt.w, "%s%s\n", strings.Repeat("  ", depth), line)
+}
+
+// MultiTracer returns a Tracer that calls all the tracers in order.
+func MultiTracer(tracers ...Tracer) Tracer { return multiTracer(tracers) }
+
+type multiTracer []Tracer
+
+func (mt multiTracer) Expanded(env *Env, form, expanded Any) {
+	for _, t := range mt {
+		t.Expanded(env, form, expanded)
+	}
+}
+
+func (mt multiTracer) Analyzed(env *Env, form Any, expr Expr, err error) {
+	for _, t := range mt {
+		t.Analyzed(env, form, expr, err)
+	}
+}
+
+func (mt multiTracer) InvokeStart(env *Env, name string, args []Any) {
+	for _, t := range mt {
+		t.InvokeStart(env, name, args)
+	}
+}
+
+func (mt multiTracer) InvokeEnd(env *Env, name string, args []Any, res Any, err error, dur time.Duration) {
+	for _, t := range mt {
+		t.InvokeEnd(env, name, args, res, err, dur)
+	}
+}
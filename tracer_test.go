@@ -0,0 +1,95 @@
+package parens_test
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/spy16/parens"
+)
+
+func TestWithTracer(t *testing.T) {
+	t.Parallel()
+
+	forEachBackend(t, func(t *testing.T, opts ...parens.Option) {
+		t.Run("WriterTracer", func(t *testing.T) {
+			var out strings.Builder
+			env := parens.New(append(opts, parens.WithCore(), parens.WithTracer(parens.NewWriterTracer(&out, false)))...)
+
+			_, err := env.Eval(readOne(t, `(def f (fn (a) (hash-map :a a)))`))
+			requireNoErr(t, err)
+			_, err = env.Fork().Eval(readOne(t, `(f 1)`))
+			requireNoErr(t, err)
+			_, err = env.Eval(readOne(t, `(get 1)`))
+			assertErr(t, err)
+
+			want := strings.Join([]string{
+				"(f 1)",
+				"  (hash-map :a 1)",
+				"  => {:a 1}",
+				"=> {:a 1}",
+				"(get 1)",
+				"!! wrong number of args: (1) passed to get",
+			}, "\n") + "\n"
+			assertEqual(t, want, out.String())
+		})
+
+		t.Run("Callbacks", func(t *testing.T) {
+			tr := &recordingTracer{}
+			env := parens.New(append(opts, parens.WithCore(), parens.WithTracer(tr))...)
+
+			_, err := env.Eval(readOne(t, `((fn (a) a) 1)`))
+			requireNoErr(t, err)
+
+			assertEqual(t, []string{
+				"analyzed a",
+				"analyzed (fn (a) a)",
+				"analyzed 1",
+				"analyzed ((fn (a) a) 1)",
+				"start (fn (a) a)",
+				"end (fn (a) a) 1",
+			}, tr.events)
+		})
+
+		t.Run("NestedCall", func(t *testing.T) {
+			tr := &recordingTracer{}
+			env := parens.New(append(opts, parens.WithCore(), parens.WithTracer(tr))...)
+
+			_, err := env.Eval(readOne(t, `(get (hash-map :a 1) :a)`))
+			requireNoErr(t, err)
+
+			assertEqual(t, []string{
+				"analyzed get",
+				"analyzed hash-map",
+				"analyzed :a",
+				"analyzed 1",
+				"analyzed (hash-map :a 1)",
+				"analyzed :a",
+				"analyzed (get (hash-map :a 1) :a)",
+				"start hash-map",
+				"end hash-map {:a 1}",
+				"start get",
+				"end get 1",
+			}, tr.events)
+		})
+	})
+}
+
+type recordingTracer struct {
+	parens.NopTracer
+	events []string
+}
+
+func (rt *recordingTracer) Analyzed(_ *parens.Env, form parens.Any, _ parens.Expr, _ error) {
+	s, _ := form.SExpr()
+	rt.events = append(rt.events, "analyzed "+s)
+}
+
+func (rt *recordingTracer) InvokeStart(_ *parens.Env, name string, _ []parens.Any) {
+	rt.events = append(rt.events, "start "+name)
+}
+
+func (rt *recordingTracer) InvokeEnd(_ *parens.Env, name string, _ []parens.Any, res parens.Any, _ error, _ time.Duration) {
+	s, _ := res.SExpr()
+	rt.events = append(rt.events, "end "+name+" "+s)
+}
@@ -1,6 +1,7 @@
 package parens
 
 import (
+	"fmt"
 	"reflect"
 	"strings"
 )
@@ -85,3 +86,79 @@ func ForEach(seq Seq, call func(item Any) (bool, error)) (err error) {
 
 	return
 }
+
+// assoc returns a new map with the key-value pairs added to m. A new map
+// is created if m is nil.
+func assoc(m Map, kvs ...Any) (Map, error) {
+	if m == nil {
+		hm, err := NewHashMap(kvs...)
+		if err != nil {
+			return nil, err
+		}
+		return hm, nil
+	}
+
+	var err error
+	for i := 0; i+1 < len(kvs); i += 2 {
+		if m, err = m.Assoc(kvs[i], kvs[i+1]); err != nil {
+			return nil, err
+		}
+	}
+	return m, nil
+}
+
+// formString returns the s-expression of the value for use in names and
+// messages.
+func formString(v Any) string {
+	if v == nil {
+		return "nil"
+	}
+	if s, err := v.SExpr(); err == nil {
+		return s
+	}
+	return fmt.Sprintf("%#v", v)
+}
+
+// asSymbol returns the symbol if the form is a Symbol or AnnotatedSymbol.
+func asSymbol(form Any) (Symbol, bool) {
+	switch sym := form.(type) {
+	case Symbol:
+		return sym, true
+	case AnnotatedSymbol:
+		return sym.Symbol, true
+	}
+	return "", false
+}
+
+// symbolMeta returns the metadata of the form if it is an AnnotatedSymbol.
+func symbolMeta(form Any) Map {
+	if as, ok := form.(AnnotatedSymbol); ok {
+		return as.meta
+	}
+	return nil
+}
+
+// mergeMeta returns the metadata with the entries of other added to meta.
+// Entries of other take precedence.
+func mergeMeta(meta, other Map) (Map, error) {
+	if meta == nil {
+		return other, nil
+	}
+
+	hm, ok := other.(*HashMap)
+	if !ok || hm == nil {
+		if other == nil {
+			return meta, nil
+		}
+		// entries of maps other than HashMap cannot be enumerated.
+		return other, nil
+	}
+
+	var err error
+	for k, v := range hm.entries {
+		if meta, err = meta.Assoc(k, v); err != nil {
+			return nil, err
+		}
+	}
+	return meta, nil
+}
@@ -1,23 +1,54 @@
 package parens
 
 import (
+	"errors"
 	"fmt"
+	"io"
 	"math"
+	"math/big"
+	"reflect"
+	"regexp"
+	"sort"
 	"strconv"
+	"strings"
+	"time"
+	"unicode"
+	"unicode/utf8"
 )
 
 var (
 	_ Any = Nil{}
 	_ Any = Int64(0)
 	_ Any = Float64(1.123123)
+	_ Any = BigInt{}
+	_ Any = Ratio{}
+	_ Any = Decimal{}
 	_ Any = Bool(true)
 	_ Any = Char('∂')
 	_ Any = String("specimen")
 	_ Any = Symbol("specimen")
 	_ Any = Keyword("specimen")
+	_ Any = Regex{}
+	_ Any = Inst{}
+	_ Any = UUID{}
+	_ Any = TaggedLiteral{}
 	_ Any = (*LinkedList)(nil)
+	_ Any = (*HashMap)(nil)
+	_ Any = (*Fn)(nil)
+	_ Any = GoFunc(nil)
 
 	_ Seq = (*LinkedList)(nil)
+	_ Map = (*HashMap)(nil)
+
+	_ Invokable = (*Fn)(nil)
+	_ Invokable = GoFunc(nil)
+
+	_ Annotatable = (*HashMap)(nil)
+	_ Annotatable = (*Fn)(nil)
+	_ Annotatable = (*LinkedList)(nil)
+	_ Annotatable = Symbol("")
+	_ Annotatable = AnnotatedSymbol{}
+	_ Positional  = (*LinkedList)(nil)
 )
 
 // Cons returns a new seq with `v` added as the first and `seq` as the rest.
@@ -91,11 +122,156 @@ func (f64 Float64) Equals(other Any) bool {
 	return isFloat && (val == f64)
 }
 
+// String returns the shortest representation of the float that reads back
+// as the same value. Infinities and NaN are printed as `##Inf`, `##-Inf` and
+// `##NaN`.
 func (f64 Float64) String() string {
-	if math.Abs(float64(f64)) >= 1e16 {
-		return fmt.Sprintf("%e", f64)
+	f := float64(f64)
+	switch {
+	case math.IsNaN(f):
+		return "##NaN"
+	case math.IsInf(f, 1):
+		return "##Inf"
+	case math.IsInf(f, -1):
+		return "##-Inf"
+	}
+
+	if abs := math.Abs(f); abs != 0 && (abs < 1e-4 || abs >= 1e16) {
+		return strconv.FormatFloat(f, 'e', -1, 64)
+	}
+
+	s := strconv.FormatFloat(f, 'f', -1, 64)
+	if !strings.ContainsRune(s, '.') {
+		// must not read back as an integer.
+		s += ".0"
+	}
+	return s
+}
+
+// BigInt represents an arbitrary-precision integer. BigInt literals are
+// written with an `N` suffix (e.g., `12N`) and integer literals that do not
+// fit in an Int64 are read as BigInt. The wrapped value must not be modified.
+type BigInt struct{ *big.Int }
+
+// SExpr returns a valid s-expression representing BigInt.
+func (bi BigInt) SExpr() (string, error) { return bi.String(), nil }
+
+// Equals returns true if the other value is also a BigInt and has same value.
+func (bi BigInt) Equals(other Any) bool {
+	val, isBig := other.(BigInt)
+	return isBig && val.Int.Cmp(bi.Int) == 0
+}
+
+func (bi BigInt) String() string { return bi.Int.String() + "N" }
+
+// Format prints the BigInt with the `N` suffix for the %v and %s verbs
+// instead of using the Format method of the embedded big.Int. Other verbs
+// (e.g., %d, %x) are formatted by big.Int.
+func (bi BigInt) Format(f fmt.State, verb rune) {
+	if verb == 'v' || verb == 's' {
+		_, _ = io.WriteString(f, bi.String())
+		return
 	}
-	return fmt.Sprintf("%f", f64)
+	bi.Int.Format(f, verb)
+}
+
+// NewRatio returns the ratio num/den in lowest terms. Returns an Int64 (or a
+// BigInt if it does not fit) if den divides num.
+func NewRatio(num, den *big.Int) (Any, error) {
+	if den.Sign() == 0 {
+		return nil, errDivideByZero()
+	}
+	return rational(new(big.Rat).SetFrac(num, den)), nil
+}
+
+// Ratio represents an exact rational number. Ratio literals are written as
+// `n/d` (e.g., `1/3`). Ratios are always in lowest terms with a denominator
+// greater than 1 (See NewRatio()). The wrapped value must not be modified.
+type Ratio struct{ *big.Rat }
+
+// SExpr returns a valid s-expression representing Ratio.
+func (r Ratio) SExpr() (string, error) { return r.String(), nil }
+
+// Equals returns true if the other value is also a Ratio and has same value.
+func (r Ratio) Equals(other Any) bool {
+	val, isRatio := other.(Ratio)
+	return isRatio && val.Rat.Cmp(r.Rat) == 0
+}
+
+func (r Ratio) String() string { return r.Rat.String() }
+
+// ParseDecimal parses a decimal number of the form `[+-]digits[.digits]` with
+// an optional exponent (e.g., `1.5e-3`). The scale of the decimal is the
+// number of digits after the point adjusted by the exponent.
+func ParseDecimal(s string) (Decimal, error) {
+	mantissa, exp := s, 0
+	if i := strings.IndexAny(s, "eE"); i >= 0 {
+		e, err := strconv.Atoi(s[i+1:])
+		if err != nil {
+			return Decimal{}, fmt.Errorf("invalid decimal '%s'", s)
+		}
+		mantissa, exp = s[:i], e
+	}
+
+	scale := 0
+	if i := strings.IndexByte(mantissa, '.'); i >= 0 {
+		scale = len(mantissa) - i - 1
+		mantissa = mantissa[:i] + mantissa[i+1:]
+	}
+
+	unscaled, ok := new(big.Int).SetString(mantissa, 10)
+	if !ok {
+		return Decimal{}, fmt.Errorf("invalid decimal '%s'", s)
+	}
+
+	scale -= exp
+	if scale < 0 {
+		unscaled.Mul(unscaled, pow10(-scale))
+		scale = 0
+	}
+	return Decimal{unscaled: unscaled, scale: scale}, nil
+}
+
+// Decimal represents an arbitrary-precision decimal number as an unscaled
+// integer and a scale, i.e., unscaled × 10^-scale. Decimal literals are
+// written with an `M` suffix (e.g., `1.50M`).
+type Decimal struct {
+	unscaled *big.Int
+	scale    int
+}
+
+// SExpr returns a valid s-expression representing Decimal.
+func (d Decimal) SExpr() (string, error) { return d.String(), nil }
+
+// Equals returns true if the other value is also a Decimal and has same
+// value. Scale is ignored (i.e., `1.0M` equals `1.00M`).
+func (d Decimal) Equals(other Any) bool {
+	val, isDecimal := other.(Decimal)
+	return isDecimal && val.Rat().Cmp(d.Rat()) == 0
+}
+
+// Scale returns the number of digits after the decimal point.
+func (d Decimal) Scale() int { return d.scale }
+
+// Rat returns the exact value of the decimal as a big.Rat.
+func (d Decimal) Rat() *big.Rat {
+	return new(big.Rat).SetFrac(d.unscaled, pow10(d.scale))
+}
+
+func (d Decimal) String() string {
+	digits := new(big.Int).Abs(d.unscaled).String()
+	if d.scale > 0 {
+		if len(digits) <= d.scale {
+			digits = strings.Repeat("0", d.scale-len(digits)+1) + digits
+		}
+		point := len(digits) - d.scale
+		digits = digits[:point] + "." + digits[point:]
+	}
+
+	if d.unscaled.Sign() < 0 {
+		digits = "-" + digits
+	}
+	return digits + "M"
 }
 
 // Bool represents a boolean Value.
@@ -121,9 +297,7 @@ func (b Bool) String() string {
 type Char rune
 
 // SExpr returns a valid s-expression representing Char.
-func (char Char) SExpr() (string, error) {
-	return fmt.Sprintf("\\%c", char), nil
-}
+func (char Char) SExpr() (string, error) { return char.String(), nil }
 
 // Equals returns true if the other Value is also a character and has same Value.
 func (char Char) Equals(other Any) bool {
@@ -131,7 +305,27 @@ func (char Char) Equals(other Any) bool {
 	return isChar && (val == char)
 }
 
-func (char Char) String() string { return fmt.Sprintf("\\%c", char) }
+// String returns the character literal. Whitespace characters with names
+// are printed using their names (e.g., `\newline`) and other non-printable
+// characters as `\uXXXX`.
+func (char Char) String() string {
+	if name, found := charNames[rune(char)]; found {
+		return "\\" + name
+	} else if !unicode.IsPrint(rune(char)) {
+		return fmt.Sprintf("\\u%04x", rune(char))
+	}
+	return "\\" + string(rune(char))
+}
+
+// charNames maps the characters to the names used in character literals.
+var charNames = map[rune]string{
+	'\t': "tab",
+	' ':  "space",
+	'\n': "newline",
+	'\r': "return",
+	'\b': "backspace",
+	'\f': "formfeed",
+}
 
 // String represents a string of characters.
 type String string
@@ -145,7 +339,190 @@ func (str String) Equals(other Any) bool {
 	return isStr && (otherStr == str)
 }
 
-func (str String) String() string { return fmt.Sprintf("\"%s\"", string(str)) }
+func (str String) String() string { return "\"" + escapeString(string(str)) + "\"" }
+
+// stringEscapes maps the characters with single rune escapes in string
+// literals to their escapes.
+var stringEscapes = map[rune]string{
+	'"':  `\"`,
+	'\\': `\\`,
+	'\a': `\a`,
+	'\b': `\b`,
+	'\f': `\f`,
+	'\n': `\n`,
+	'\r': `\r`,
+	'\t': `\t`,
+	'\v': `\v`,
+}
+
+// escapeString escapes the quotes, backslashes, non-printable characters and
+// the bytes that are not valid UTF-8 in s so that the string literal reads
+// back as s.
+func escapeString(s string) string {
+	var b strings.Builder
+	for i := 0; i < len(s); {
+		r, size := utf8.DecodeRuneInString(s[i:])
+		if r == utf8.RuneError && size == 1 {
+			fmt.Fprintf(&b, `\x%02x`, s[i])
+		} else if esc, found := stringEscapes[r]; found {
+			b.WriteString(esc)
+		} else if unicode.IsPrint(r) {
+			b.WriteString(s[i : i+size])
+		} else if r <= 0xFFFF {
+			fmt.Fprintf(&b, `\u%04x`, r)
+		} else {
+			fmt.Fprintf(&b, `\U%08x`, r)
+		}
+		i += size
+	}
+	return b.String()
+}
+
+// NewRegex compiles the pattern (See regexp/syntax for the syntax) into a
+// Regex.
+func NewRegex(pattern string) (Regex, error) {
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		return Regex{}, err
+	}
+
+	anchored, err := regexp.Compile(`^(?:` + pattern + `)$`)
+	if err != nil {
+		return Regex{}, err
+	}
+	return Regex{Regexp: re, anchored: anchored}, nil
+}
+
+// Regex represents a compiled regular expression. Regex literals are written
+// as `#"pattern"`.
+type Regex struct {
+	*regexp.Regexp
+	anchored *regexp.Regexp // matches the whole input only.
+}
+
+// SExpr returns a valid s-expression representing Regex.
+func (re Regex) SExpr() (string, error) { return re.String(), nil }
+
+// Equals returns true if the other value is also a Regex with the same
+// pattern.
+func (re Regex) Equals(other Any) bool {
+	val, isRegex := other.(Regex)
+	return isRegex && val.Regexp.String() == re.Regexp.String()
+}
+
+// String returns the regex literal. Quotes in the pattern are escaped.
+func (re Regex) String() string {
+	if re.Regexp == nil {
+		return `#""`
+	}
+	pattern := re.Regexp.String()
+
+	var b strings.Builder
+	b.WriteString(`#"`)
+	for i := 0; i < len(pattern); i++ {
+		switch pattern[i] {
+		case '\\':
+			b.WriteByte('\\')
+			if i+1 < len(pattern) {
+				i++
+				b.WriteByte(pattern[i])
+			}
+		case '"':
+			b.WriteString(`\"`)
+		default:
+			b.WriteByte(pattern[i])
+		}
+	}
+	b.WriteByte('"')
+	return b.String()
+}
+
+// Inst represents an instant in time. Inst literals are written as `#inst
+// "2006-01-02T15:04:05Z"` (RFC3339).
+type Inst struct{ time.Time }
+
+// SExpr returns a valid s-expression representing Inst.
+func (inst Inst) SExpr() (string, error) { return inst.String(), nil }
+
+// Equals returns true if the other value is an Inst of the same instant.
+func (inst Inst) Equals(other Any) bool {
+	val, isInst := other.(Inst)
+	return isInst && val.Time.Equal(inst.Time)
+}
+
+func (inst Inst) String() string {
+	return "#inst \"" + inst.Format(time.RFC3339Nano) + "\""
+}
+
+// ParseUUID parses the UUID in the canonical hex format (e.g.,
+// "f81d4fae-7dec-11d0-a765-00a0c91e6bf6").
+func ParseUUID(s string) (UUID, error) {
+	var id UUID
+	if len(s) != 36 {
+		return id, fmt.Errorf("invalid uuid: '%s'", s)
+	}
+
+	j := 0
+	for i := 0; i < len(s); {
+		if i == 8 || i == 13 || i == 18 || i == 23 {
+			if s[i] != '-' {
+				return id, fmt.Errorf("invalid uuid: '%s'", s)
+			}
+			i++
+			continue
+		}
+
+		v, err := strconv.ParseUint(s[i:i+2], 16, 8)
+		if err != nil {
+			return id, fmt.Errorf("invalid uuid: '%s'", s)
+		}
+		id[j] = byte(v)
+		i, j = i+2, j+1
+	}
+	return id, nil
+}
+
+// UUID represents a universally unique identifier. UUID literals are written
+// as `#uuid "f81d4fae-7dec-11d0-a765-00a0c91e6bf6"`.
+type UUID [16]byte
+
+// SExpr returns a valid s-expression representing UUID.
+func (id UUID) SExpr() (string, error) { return id.String(), nil }
+
+// Equals returns true if the other value is the same UUID.
+func (id UUID) Equals(other Any) bool {
+	val, isUUID := other.(UUID)
+	return isUUID && val == id
+}
+
+func (id UUID) String() string {
+	return fmt.Sprintf("#uuid \"%x-%x-%x-%x-%x\"", id[0:4], id[4:6], id[6:8], id[8:10], id[10:16])
+}
+
+// TaggedLiteral represents a tagged literal (e.g., `#point (1 2)`) that was
+// read without a data reader for the tag.
+type TaggedLiteral struct {
+	Tag  Symbol
+	Form Any
+}
+
+// SExpr returns a valid s-expression representing TaggedLiteral.
+func (tl TaggedLiteral) SExpr() (string, error) {
+	if tl.Form == nil {
+		return "#" + string(tl.Tag) + " nil", nil
+	}
+
+	s, err := tl.Form.SExpr()
+	if err != nil {
+		return "", err
+	}
+	return "#" + string(tl.Tag) + " " + s, nil
+}
+
+func (tl TaggedLiteral) String() string {
+	s, _ := tl.SExpr()
+	return s
+}
 
 // Symbol represents a lisp symbol Value.
 type Symbol string
@@ -154,13 +531,40 @@ type Symbol string
 func (sym Symbol) SExpr() (string, error) { return string(sym), nil }
 
 // Equals returns true if the other Value is also a symbol and has same Value.
+// Metadata of the symbols is not compared.
 func (sym Symbol) Equals(other Any) bool {
-	otherSym, isSym := other.(Symbol)
+	otherSym, isSym := asSymbol(other)
 	return isSym && (sym == otherSym)
 }
 
 func (sym Symbol) String() string { return string(sym) }
 
+// Meta returns nil since a Symbol has no metadata. See AnnotatedSymbol.
+func (sym Symbol) Meta() Map { return nil }
+
+// WithMeta returns the symbol with the given metadata.
+func (sym Symbol) WithMeta(meta Map) (Any, error) {
+	return AnnotatedSymbol{Symbol: sym, meta: meta}, nil
+}
+
+// AnnotatedSymbol is a Symbol with metadata (e.g., `^:private name`). It is
+// analyzed same as the symbol and the metadata does not affect equality.
+type AnnotatedSymbol struct {
+	Symbol
+	meta Map
+}
+
+// Equals returns true if the other Value is a symbol with the same name.
+func (as AnnotatedSymbol) Equals(other Any) bool { return as.Symbol.Equals(other) }
+
+// Meta returns the metadata of the symbol.
+func (as AnnotatedSymbol) Meta() Map { return as.meta }
+
+// WithMeta returns the symbol with the metadata replaced.
+func (as AnnotatedSymbol) WithMeta(meta Map) (Any, error) {
+	return AnnotatedSymbol{Symbol: as.Symbol, meta: meta}, nil
+}
+
 // Keyword represents a keyword Value.
 type Keyword string
 
@@ -180,6 +584,46 @@ type LinkedList struct {
 	count int
 	first Any
 	rest  Seq
+	pos   Position
+	meta  Map
+}
+
+// Meta returns the metadata of the list.
+func (ll *LinkedList) Meta() Map {
+	if ll == nil {
+		return nil
+	}
+	return ll.meta
+}
+
+// WithMeta returns a copy of the list with the given metadata. Metadata is
+// not carried over to the lists derived from the list (e.g., using Conj).
+func (ll *LinkedList) WithMeta(meta Map) (Any, error) {
+	cp := LinkedList{}
+	if ll != nil {
+		cp = *ll
+	}
+	cp.meta = meta
+	return &cp, nil
+}
+
+// Pos returns the position in source the list was read from. Zero value is
+// returned if the position is not known.
+func (ll *LinkedList) Pos() Position {
+	if ll == nil {
+		return Position{}
+	}
+	return ll.pos
+}
+
+// WithPos returns a copy of the list annotated with the given position.
+func (ll *LinkedList) WithPos(pos Position) *LinkedList {
+	if ll == nil {
+		return nil
+	}
+	cp := *ll
+	cp.pos = pos
+	return &cp
 }
 
 // SExpr returns a valid s-expression for LinkedList.
@@ -232,3 +676,230 @@ func (ll *LinkedList) Count() (int, error) {
 
 	return ll.count, nil
 }
+
+// NewHashMap returns a new HashMap containing the given key-value pairs.
+func NewHashMap(kvs ...Any) (*HashMap, error) {
+	if len(kvs)%2 != 0 {
+		return nil, errors.New("expecting even number of forms for map")
+	}
+
+	hm := &HashMap{entries: make(map[Any]Any, len(kvs)/2)}
+	for i := 0; i < len(kvs); i += 2 {
+		if err := checkHashable(kvs[i]); err != nil {
+			return nil, err
+		}
+		hm.entries[kvs[i]] = kvs[i+1]
+	}
+
+	return hm, nil
+}
+
+// HashMap implements an immutable Map using a native Go map. Every update
+// copies the entries and hence it is suitable only for small maps such as
+// metadata.
+type HashMap struct {
+	entries map[Any]Any
+	meta    Map
+}
+
+// SExpr returns a valid s-expression for HashMap. Entries are sorted by the
+// s-expression of the keys to keep the output stable.
+func (hm *HashMap) SExpr() (string, error) {
+	if hm == nil {
+		return "{}", nil
+	}
+
+	entries := make([]string, 0, len(hm.entries))
+	for k, v := range hm.entries {
+		ks, err := k.SExpr()
+		if err != nil {
+			return "", err
+		}
+
+		vs, err := v.SExpr()
+		if err != nil {
+			return "", err
+		}
+		entries = append(entries, ks+" "+vs)
+	}
+	sort.Strings(entries)
+
+	return "{" + strings.Join(entries, ", ") + "}", nil
+}
+
+// Count returns the number of entries in the map.
+func (hm *HashMap) Count() (int, error) {
+	if hm == nil {
+		return 0, nil
+	}
+	return len(hm.entries), nil
+}
+
+// HasKey returns true if the map contains an entry for the key.
+func (hm *HashMap) HasKey(key Any) bool {
+	_, found := hm.EntryAt(key)
+	return found
+}
+
+// EntryAt returns the value associated with the key if it exists.
+func (hm *HashMap) EntryAt(key Any) (Any, bool) {
+	if hm == nil || checkHashable(key) != nil {
+		return nil, false
+	}
+	v, found := hm.entries[key]
+	return v, found
+}
+
+// Assoc returns a new map with the key-value pair added.
+func (hm *HashMap) Assoc(key, val Any) (Map, error) {
+	if err := checkHashable(key); err != nil {
+		return nil, err
+	}
+
+	res := &HashMap{entries: map[Any]Any{}}
+	if hm != nil {
+		res.meta = hm.meta
+		for k, v := range hm.entries {
+			res.entries[k] = v
+		}
+	}
+	res.entries[key] = val
+
+	return res, nil
+}
+
+// Meta returns the metadata associated with the map.
+func (hm *HashMap) Meta() Map {
+	if hm == nil {
+		return nil
+	}
+	return hm.meta
+}
+
+// WithMeta returns a copy of the map with the given metadata.
+func (hm *HashMap) WithMeta(meta Map) (Any, error) {
+	res := &HashMap{meta: meta}
+	if hm != nil {
+		res.entries = hm.entries
+	}
+	return res, nil
+}
+
+// Fn represents a function value created by the fn special form.
+type Fn struct {
+	Name     string
+	Params   []string
+	Variadic bool
+	Body     Expr
+
+	meta     Map
+	locals   []string
+	captures []Capture
+	closure  []Any
+}
+
+// SExpr returns a string representation of the function. Functions cannot
+// be read back.
+func (fn *Fn) SExpr() (string, error) {
+	if fn.Name == "" {
+		return "#<fn>", nil
+	}
+	return fmt.Sprintf("#<fn %s>", fn.Name), nil
+}
+
+// Meta returns the metadata associated with the function.
+func (fn *Fn) Meta() Map { return fn.meta }
+
+// WithMeta returns a copy of the function with the given metadata.
+func (fn *Fn) WithMeta(meta Map) (Any, error) {
+	cp := *fn
+	cp.meta = meta
+	return &cp, nil
+}
+
+// Invoke binds the arguments to the parameter slots in the top stack frame
+// and evaluates the body.
+func (fn *Fn) Invoke(env *Env, args ...Any) (Any, error) {
+	required, max := len(fn.Params), len(fn.Params)
+	if fn.Variadic {
+		required, max = required-1, -1
+	}
+
+	if err := checkArity(fn.displayName(), args, required, max); err != nil {
+		return nil, err
+	}
+
+	if len(env.stack) == 0 {
+		env.push(stackFrame{Name: fn.Name, Args: args})
+		defer env.pop()
+	}
+
+	top := &env.stack[len(env.stack)-1]
+	slots := len(fn.locals)
+	if min := len(fn.Params) + 1; slots < min {
+		// function was not created by FnExpr.
+		slots = min
+	}
+
+	// locals live on the value stack of the env until the call returns.
+	// Closures and forks copy the values they need.
+	defer env.release(len(env.vals))
+	top.names = fn.locals
+	top.Locals = env.alloca(slots)
+
+	for i := 0; i < required; i++ {
+		top.Locals[i] = args[i]
+	}
+	if fn.Variadic {
+		if err := env.Alloc(len(args) - required); err != nil {
+			return nil, err
+		}
+		top.Locals[required] = NewList(args[required:]...)
+	}
+	if fn.Name != "" {
+		top.Locals[len(fn.Params)] = fn
+	}
+
+	for i, c := range fn.captures {
+		top.Locals[c.To] = fn.closure[i]
+	}
+
+	return fn.Body.Eval(env)
+}
+
+func (fn *Fn) displayName() string {
+	if fn.Name == "" {
+		return "fn"
+	}
+	return fn.Name
+}
+
+// Arglist returns the parameter list of the function as a list of symbols.
+func (fn *Fn) Arglist() Seq {
+	var params []Any
+	for i, p := range fn.Params {
+		if fn.Variadic && i == len(fn.Params)-1 {
+			params = append(params, Symbol("&"))
+		}
+		params = append(params, Symbol(p))
+	}
+	return NewList(params...)
+}
+
+// GoFunc is an Invokable implemented as a native Go function. The args are
+// valid only until the function returns and must be copied to be retained.
+type GoFunc func(env *Env, args ...Any) (Any, error)
+
+// SExpr returns a string representation of the function. Functions cannot
+// be read back.
+func (fn GoFunc) SExpr() (string, error) { return "#<go-fn>", nil }
+
+// Invoke simply calls the wrapped function.
+func (fn GoFunc) Invoke(env *Env, args ...Any) (Any, error) { return fn(env, args...) }
+
+func checkHashable(key Any) error {
+	if key == nil || !reflect.TypeOf(key).Comparable() {
+		return fmt.Errorf("value of type '%s' cannot be used as map key", reflect.TypeOf(key))
+	}
+	return nil
+}
@@ -0,0 +1,105 @@
+package parens
+
+import (
+	"fmt"
+	"sync"
+	"sync/atomic"
+)
+
+var _ Annotated = (*Var)(nil)
+
+// WatchFn is called by a Var after it has been re-defined.
+type WatchFn func(v *Var, old, new Any)
+
+// NewVar returns a new Var with given name, root value and metadata.
+func NewVar(name string, val Any, meta Map) *Var {
+	v := &Var{name: name}
+	v.state.Store(&varState{val: val, meta: meta})
+	return v
+}
+
+// Var is a named reference stored in the global bindings of an Env. Along
+// with the root value, a Var carries a metadata map (e.g., `:doc`, `:line`)
+// and notifies the registered watchers whenever it is re-defined. Var is
+// safe for concurrent use.
+type Var struct {
+	name  string
+	state atomic.Value
+
+	mu      sync.Mutex
+	watches map[string]WatchFn
+}
+
+type varState struct {
+	val  Any
+	meta Map
+}
+
+// SExpr returns a valid s-expression referring to the Var.
+func (v *Var) SExpr() (string, error) {
+	return fmt.Sprintf("(var %s)", v.name), nil
+}
+
+// Name returns the name of the Var.
+func (v *Var) Name() string { return v.name }
+
+// Deref returns the root value of the Var.
+func (v *Var) Deref() Any { return v.load().val }
+
+// Meta returns the metadata associated with the Var.
+func (v *Var) Meta() Map { return v.load().meta }
+
+// Dynamic returns true if the Var can be re-bound using binding. A Var is
+// dynamic if it follows the earmuff naming convention or has `:dynamic`
+// set in its metadata.
+func (v *Var) Dynamic() bool {
+	if IsDynamic(v.name) {
+		return true
+	}
+
+	meta := v.Meta()
+	if meta == nil {
+		return false
+	}
+	dyn, _ := meta.EntryAt(Keyword("dynamic"))
+	return IsTruthy(dyn)
+}
+
+// Define replaces the root value and metadata of the Var and notifies all
+// the registered watchers.
+func (v *Var) Define(val Any, meta Map) {
+	old := v.load().val
+	v.state.Store(&varState{val: val, meta: meta})
+
+	v.mu.Lock()
+	watches := make([]WatchFn, 0, len(v.watches))
+	for _, fn := range v.watches {
+		watches = append(watches, fn)
+	}
+	v.mu.Unlock()
+
+	for _, fn := range watches {
+		fn(v, old, val)
+	}
+}
+
+// AddWatch registers fn to be called whenever the Var is re-defined. Adding
+// a watch with an existing key replaces it.
+func (v *Var) AddWatch(key string, fn WatchFn) {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+
+	if v.watches == nil {
+		v.watches = map[string]WatchFn{}
+	}
+	v.watches[key] = fn
+}
+
+// RemoveWatch removes the watch registered with the key.
+func (v *Var) RemoveWatch(key string) {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	delete(v.watches, key)
+}
+
+func (v *Var) load() *varState { return v.state.Load().(*varState) }
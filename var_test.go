@@ -0,0 +1,78 @@
+package parens_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/spy16/parens"
+	"github.com/spy16/parens/reader"
+)
+
+func TestVar_Define(t *testing.T) {
+	t.Parallel()
+
+	v := parens.NewVar("limit", parens.Int64(10), nil)
+
+	var calls []parens.Any
+	v.AddWatch("test", func(got *parens.Var, old, new parens.Any) {
+		if got != v {
+			t.Errorf("watch called with unexpected var: %#v", got)
+		}
+		calls = append(calls, old, new)
+	})
+
+	v.Define(parens.Int64(20), nil)
+	assertEqual(t, parens.Int64(20), v.Deref())
+	assertEqual(t, []parens.Any{parens.Int64(10), parens.Int64(20)}, calls)
+
+	v.RemoveWatch("test")
+	v.Define(parens.Int64(30), nil)
+	assertEqual(t, 2, len(calls))
+}
+
+func TestVar_Dynamic(t *testing.T) {
+	t.Parallel()
+
+	meta, err := parens.NewHashMap(parens.Keyword("dynamic"), parens.Bool(true))
+	requireNoErr(t, err)
+
+	assertEqual(t, true, parens.NewVar("*out*", parens.Nil{}, nil).Dynamic())
+	assertEqual(t, true, parens.NewVar("user", parens.Nil{}, meta).Dynamic())
+	assertEqual(t, false, parens.NewVar("user", parens.Nil{}, nil).Dynamic())
+}
+
+func TestDef_Meta(t *testing.T) {
+	t.Parallel()
+
+	env := parens.New(parens.WithCore())
+
+	rd := reader.New(strings.NewReader(`
+(def add "Returns the sum." (fn (a & more) a))`), reader.WithPositions())
+	rd.File = "rules.lisp"
+
+	form, err := rd.One()
+	requireNoErr(t, err)
+
+	var redefined bool
+	_, err = env.Eval(form)
+	requireNoErr(t, err)
+
+	v, found := env.Var("add")
+	if !found {
+		t.Fatalf("var 'add' not found after def")
+	}
+	v.AddWatch("test", func(_ *parens.Var, _, _ parens.Any) { redefined = true })
+
+	meta, err := env.Eval(readOne(t, `(meta (var add))`))
+	requireNoErr(t, err)
+
+	got, err := meta.SExpr()
+	requireNoErr(t, err)
+	assertEqual(t, `{:arglists ((a & more)), :doc "Returns the sum.", :file "rules.lisp", :line 2}`, got)
+
+	_, err = env.Eval(readOne(t, `(def add 10)`))
+	requireNoErr(t, err)
+	assertEqual(t, true, redefined)
+	assertEqual(t, parens.Int64(10), v.Deref())
+	assertEqual(t, nil, v.Meta())
+}